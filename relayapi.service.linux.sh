@@ -15,6 +15,18 @@ USER=$(whoami)
 # Path to the service file
 SERVICE_FILE="/etc/systemd/system/$SERVICE_NAME.service"
 
+# Optional systemd socket activation: ./relayapi.service.linux.sh --socket 8840
+# systemd then owns the listening socket, so restarts queue new connections instead of refusing them
+SOCKET_LISTEN=""
+if [ "$1" = "--socket" ]; then
+  SOCKET_LISTEN="$2"
+  if [ -z "$SOCKET_LISTEN" ]; then
+    echo "Error: --socket requires a listen address, e.g. --socket 8840 or --socket /run/relayapi.sock"
+    exit 1
+  fi
+fi
+SOCKET_FILE="/etc/systemd/system/$SERVICE_NAME.socket"
+
 # Check if the executable exists and has execute permissions
 echo "Checking if executable '$EXECUTABLE' exists and is executable..."
 if [ ! -x "$EXECUTABLE" ]; then
@@ -23,17 +35,45 @@ if [ ! -x "$EXECUTABLE" ]; then
 fi
 echo "Executable check passed."
 
+# Create the systemd socket file (requires sudo)
+SOCKET_UNIT=""
+if [ -n "$SOCKET_LISTEN" ]; then
+  echo "Creating systemd socket file: '$SOCKET_FILE'..."
+  SOCKET_CONTENT="
+[Unit]
+Description=Relay API Server Socket
+
+[Socket]
+ListenStream=$SOCKET_LISTEN
+FileDescriptorName=relayapi
+NoDelay=true
+
+[Install]
+WantedBy=sockets.target
+"
+  if sudo sh -c "echo \"$SOCKET_CONTENT\" > '$SOCKET_FILE'" && sudo chmod 644 "$SOCKET_FILE"; then
+    echo "Socket file created successfully."
+  else
+    echo "Error: Failed to create socket file."
+    exit 1
+  fi
+  SOCKET_UNIT="
+Requires=$SERVICE_NAME.socket
+After=$SERVICE_NAME.socket"
+fi
+
 # Create the systemd service file (requires sudo)
 echo "Creating systemd service file: '$SERVICE_FILE'..."
 SERVICE_CONTENT="
 [Unit]
 Description=Relay API Server
-After=network.target
+After=network.target$SOCKET_UNIT
 
 [Service]
 WorkingDirectory=$SCRIPT_DIR
 User=$USER
 ExecStart=$EXECUTABLE
+ExecReload=/bin/kill -HUP \\\$MAINPID
 Restart=on-failure
 RestartSec=5
 
@@ -70,6 +110,17 @@ else
   exit 1
 fi
 
+# Enable and start the socket first, so it is listening before the service starts (requires sudo)
+if [ -n "$SOCKET_LISTEN" ]; then
+  echo "Enabling and starting socket '$SERVICE_NAME.socket'..."
+  if sudo systemctl enable --now "$SERVICE_NAME.socket"; then
+    echo "Socket enabled successfully."
+  else
+    echo "Error: Failed to enable socket."
+    exit 1
+  fi
+fi
+
 # Enable the service to start on boot (requires sudo)
 echo "Enabling service to start on boot..."
 if sudo systemctl enable "$SERVICE_NAME"; then
@@ -89,3 +140,6 @@ else
 fi
 
 echo "Service '$SERVICE_NAME' has been registered and started."
+if [ -n "$SOCKET_LISTEN" ]; then
+  echo "Socket activation is enabled on '$SOCKET_LISTEN'. Restart with 'sudo systemctl restart $SERVICE_NAME' without refusing connections."
+fi
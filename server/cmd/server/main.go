@@ -5,22 +5,30 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/cache"
+	"relayapi/server/internal/certs"
 	"relayapi/server/internal/config"
 	"relayapi/server/internal/handlers"
+	"relayapi/server/internal/listen"
+	"relayapi/server/internal/logging"
 	"relayapi/server/internal/middleware"
 	"relayapi/server/internal/middleware/logger"
 	"relayapi/server/internal/services"
 	"relayapi/server/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/term"
 	"golang.org/x/time/rate"
 )
 
@@ -28,29 +36,41 @@ import (
 const Version = "v1.0.10" // 这个值会在构建时被替换
 
 var (
-	debugMode bool
-	logWriter io.Writer
+	debugMode    bool
+	headlessMode bool
+	logWriter    io.Writer
 )
 
-func setupLogging(debug bool) {
-	if debug {
+// setupLogging 设置默认的结构化日志，写入的密钥、令牌和 api_key 等字段会自动脱敏
+func setupLogging(debug, headless bool) {
+	if headless {
+		// 无界面模式：JSON 格式的分级日志写入标准输出，log 包的输出也写入其中（INFO 级别）
+		level := slog.LevelInfo
+		gin.SetMode(gin.ReleaseMode)
+		if debug {
+			level = slog.LevelDebug
+			gin.SetMode(gin.DebugMode)
+		}
+		logWriter = os.Stdout
+		slog.SetDefault(logging.New(logWriter, true, level))
+		// Gin 的路由调试信息不是 JSON，不输出
+		gin.DefaultWriter = io.Discard
+	} else if debug {
 		// 创建或打开debug.log文件
 		logFile, err := os.OpenFile("debug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 		if err != nil {
-			log.Fatalf("❌ Failed to open debug.log: %v", err)
+			fatal("Failed to open debug.log", err)
 		}
-		// 设置日志输出到文件，并添加时间戳
+		// 设置日志输出到文件，包括调试级别的日志；log 包的输出转为 INFO 级别
 		logWriter = logFile
-		log.SetOutput(logWriter)
-		log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+		slog.SetDefault(logging.New(logWriter, false, slog.LevelDebug))
 		// 设置 Gin 的日志输出到同一个文件
 		gin.DefaultWriter = logWriter
 		gin.SetMode(gin.DebugMode)
 	} else {
 		// 如果不是debug模式，禁用所有日志输出
 		logWriter = io.Discard
-		log.SetOutput(logWriter)
-		log.SetFlags(0)
+		slog.SetDefault(logging.Discard())
 		gin.DefaultWriter = io.Discard
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -58,18 +78,34 @@ func setupLogging(debug bool) {
 
 func main() {
 	// 解析命令行参数
-	var genConfig string
+	var genConfig, schemaName string
+	var checkConfig bool
 	serverConfig := flag.String("config", "config.json", "服务器配置文件路径")
 	clientConfig := flag.String("rai", "default.rai", "客户端配置文件路径或目录 (.rai)")
 	flag.StringVar(&genConfig, "gen", "", "生成客户端配置 (格式: [host:port] 或 help)")
+	flag.BoolVar(&checkConfig, "check-config", false, "检查配置文件并打印全部问题后退出")
+	flag.StringVar(&schemaName, "schema", "", "输出配置文件的 JSON Schema 后退出 (server 或 client)")
 	flag.BoolVar(&debugMode, "debug", false, "启用调试日志输出到debug.log")
 	flag.BoolVar(&debugMode, "d", false, "启用调试日志输出到debug.log (简写)")
+	flag.BoolVar(&headlessMode, "headless", false, "无界面模式：不显示统计界面，以 JSON 格式向标准输出写入日志（标准输出不是终端时默认启用）")
+
+	// 子命令：relayapi-server token <mint|inspect|validate>、relayapi-server rotate
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "token":
+			utils.OnceCMDToken(os.Args[2:])
+		case "rotate":
+			utils.OnceCMDRotateKey(os.Args[2:])
+		}
+	}
 
 	// 自定义 Usage
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\n使用 --gen help 查看配置生成的详细说明\n")
+		fmt.Fprintf(os.Stderr, "使用 token help 查看令牌签发、查看和校验的说明\n")
+		fmt.Fprintf(os.Stderr, "使用 rotate --help 查看 .rai 密钥轮换的说明\n")
 	}
 
 	flag.Parse()
@@ -78,79 +114,102 @@ func main() {
 	if isFlagPassed("gen") {
 		utils.OnceCMDGenerateClientConfig(genConfig)
 	}
+	if isFlagPassed("schema") {
+		utils.OnceCMDSchema(schemaName)
+	}
+	if checkConfig {
+		utils.OnceCMDCheckConfig(*serverConfig, *clientConfig)
+	}
+
+	// 没有指定 --headless 时，标准输出不是终端（容器、systemd、重定向到文件）则使用无界面模式
+	if !isFlagPassed("headless") {
+		headlessMode = !term.IsTerminal(int(os.Stdout.Fd()))
+	}
 
 	// 设置日志
-	setupLogging(debugMode)
+	setupLogging(debugMode, headlessMode)
 
 	// 创建停止通道
 	stopChan := make(chan struct{})
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// SIGHUP 在启动时注册且不再注销，监控启动前和关闭期间收到的 SIGHUP 不会结束进程
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 
-	// 渐变色数组
-	gradientColors := []string{
-		"\033[38;5;51m", // 浅青色
-		"\033[38;5;45m", // 青色
-		"\033[38;5;39m", // 深青色
-		"\033[38;5;33m", // 蓝色
-		"\033[38;5;27m", // 深蓝色
-	}
-
-	// 打印启动标题
-	title := "=== RelayAPI Server Starting ==="
-	colorIdx := 0
-	for _, char := range title {
-		fmt.Print(gradientColors[colorIdx%len(gradientColors)], string(char))
-		colorIdx++
+	// 打印启动标题，无界面模式下不输出 ANSI 控制序列
+	if !headlessMode {
+		printTitle()
 	}
-	fmt.Print("\033[0m\n\n")
 
-	log.Printf("📚 Loading configuration files %s", *serverConfig)
-	log.Printf("📚 Loading rai file: %s", *clientConfig)
+	slog.Info("Loading configuration files", "config", *serverConfig, "rai", *clientConfig)
 
 	// 加载配置
 	cfg, err := config.LoadConfig(*serverConfig, *clientConfig)
 	if err != nil {
-		log.Fatalf("❌ Failed to load config: %v", err)
+		fatal("Failed to load config", err)
 	}
 
 	// 验证配置
 	if err := config.ValidateConfig(cfg); err != nil {
-		log.Fatalf("❌ Invalid config: %v", err)
+		fatal("Invalid config", err)
 	}
 
 	// 记录运行模式
-	if debugMode {
-		log.Println("🔧 Running in DEBUG mode")
-	} else {
-		log.Println("🔧 Running in RELEASE mode")
-	}
+	slog.Info("Server starting", "version", Version, "mode", gin.Mode(), "headless", headlessMode)
 
-	// 创建 Gin 引擎
-	router := gin.New()
+	// 打开监听之前获取 systemd 套接字激活传入的套接字
+	activation, err := listen.Systemd()
+	if err != nil {
+		fatal("Failed to read systemd sockets", err)
+	}
+	listeners := cfg.Server.ListenerConfigs()
+	if len(cfg.Server.Listeners) == 0 && activation.Len() > 0 {
+		// 由 systemd 套接字激活启动且没有配置 listeners 时，主监听使用传入的套接字
+		listeners[0].Network, listeners[0].Address = config.NetworkSystemd, ""
+		if err := cfg.Server.CheckAdminToken(listeners); err != nil {
+			fatal("Invalid config", err)
+		}
+	}
 
 	// 创建统计服务
-	serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Server.Host, cfg.Server.Server.Port)
-	statsService := services.NewStats(Version, serverAddr, cfg.Clients)
+	serverAddr := listenerAddrs(listeners)
+	statsService := services.NewStats(Version, serverAddr, cfg.ClientConfigs)
 
-	// 启动统计信息显示
-	go statsService.StartConsoleDisplay(stopChan)
+	// 启动统计信息显示，无界面模式下只通过 /health 提供统计信息
+	if !headlessMode {
+		go statsService.StartConsoleDisplay(stopChan)
+	}
+
+	slog.Debug("Initializing middleware")
+	// 关闭服务器时拒绝新请求并等待进行中的请求完成
+	drainer := middleware.NewDrainer()
 
-	log.Println("🔧 Initializing middleware...")
-	// 添加路径规范化中间件
-	router.Use(middleware.PathNormalizationMiddleware())
+	// 日志写入器可以随 config.json 热重载替换
+	logWriters := logger.NewWriterSet(&cfg.Server, slog.Default())
 
-	// 添加日志中间件
-	router.Use(logger.Middleware(cfg))
+	// 所有监听共用的中间件：路径规范化、请求体大小限制（需要在日志中间件读取请求体之前）和日志
+	commonMiddleware := []gin.HandlerFunc{
+		drainer.Middleware(),
+		middleware.PathNormalizationMiddleware(),
+		middleware.BodyLimit(cfg, "/relayapi"),
+		logger.Middleware(cfg, logWriters, slog.Default()),
+	}
 
 	// 创建代理服务
-	proxyService := services.NewProxyService()
+	proxyService := services.NewProxyService(&cfg.Server)
+
+	// 创建响应缓存（未启用时为 nil）
+	responseCache, err := cache.New(&cfg.Server)
+	if err != nil {
+		fatal("Failed to create response cache", err)
+	}
 
 	// 创建 API 处理器
-	apiHandler := handlers.NewAPIHandler(proxyService)
+	apiHandler := handlers.NewAPIHandler(proxyService, responseCache, cfg)
 
-	// 健康检查路由
-	router.GET("/health", func(c *gin.Context) {
+	// 健康检查处理器
+	healthHandler := func(c *gin.Context) {
 		uptime := statsService.GetUptime()
 		totalReqs := atomic.LoadUint64(&statsService.TotalRequests)
 		stats := map[string]interface{}{
@@ -158,6 +217,11 @@ func main() {
 			"total_requests":      totalReqs,
 			"successful_requests": atomic.LoadUint64(&statsService.SuccessfulRequests),
 			"failed_requests":     atomic.LoadUint64(&statsService.FailedRequests),
+			"canceled_requests":   atomic.LoadUint64(&statsService.CanceledRequests),
+			"cache_hits":          atomic.LoadUint64(&statsService.CacheHits),
+			"cache_misses":        atomic.LoadUint64(&statsService.CacheMisses),
+			"semantic_cache_hits": atomic.LoadUint64(&statsService.SemanticHits),
+			"coalesced_requests":  atomic.LoadUint64(&statsService.CoalescedRequests),
 			"bytes_received":      atomic.LoadUint64(&statsService.BytesReceived),
 			"bytes_sent":          atomic.LoadUint64(&statsService.BytesSent),
 			"tps":                 float64(totalReqs) / uptime.Seconds(),
@@ -168,76 +232,299 @@ func main() {
 			"version": Version,
 			"stats":   stats,
 		})
-	})
+	}
 
-	// API 路由组
-	api := router.Group("/relayapi")
-	{
-		// 创建全局限流器和 IP 限流器
-		globalLimiter := rate.NewLimiter(rate.Limit(cfg.Server.RateLimit.RequestsPerSecond), cfg.Server.RateLimit.Burst)
-		ipLimiter := middleware.NewIPRateLimiter(
-			rate.Limit(cfg.Server.RateLimit.IPLimit.RequestsPerSecond),
-			cfg.Server.RateLimit.IPLimit.Burst,
-		)
-
-		// 添加统计中间件
-		api.Use(func(c *gin.Context) {
-			statsService.IncrementTotal()
-			c.Next()
-			status := c.Writer.Status()
-			if status >= 400 {
-				statsService.IncrementFailed()
-				statsService.IncrementErrorStatus(status)
-			} else {
-				statsService.IncrementSuccess()
-			}
-			// 记录请求和响应大小
+	// 创建全局限流器和 IP 限流器（管理接口可以在运行时调整）
+	globalLimiter := rate.NewLimiter(rate.Limit(cfg.Server.RateLimit.RequestsPerSecond), cfg.Server.RateLimit.Burst)
+	ipLimiter := middleware.NewIPRateLimiter(
+		rate.Limit(cfg.Server.RateLimit.IPLimit.RequestsPerSecond),
+		cfg.Server.RateLimit.IPLimit.Burst,
+	)
+
+	// config.json 修改或收到 SIGHUP 时热重载限流、日志和上游超时配置，
+	// 新配置无效时保留当前配置；其他配置项仍需要重启才能生效。
+	// 文件中的 rate_limit 没有变化时保留通过管理接口所做的调整
+	fileRateLimit := cfg.Server.RateLimit
+	go config.WatchServerConfig(*serverConfig, func(server *config.ServerConfig) error {
+		// 日志写入器是唯一可能失败的步骤，先替换它，失败时其余配置保持不变
+		if err := logWriters.Reload(server); err != nil {
+			return err
+		}
+		if server.RateLimit != fileRateLimit {
+			fileRateLimit = server.RateLimit
+			globalLimiter.SetLimit(rate.Limit(server.RateLimit.RequestsPerSecond))
+			globalLimiter.SetBurst(server.RateLimit.Burst)
+			ipLimiter.SetLimit(rate.Limit(server.RateLimit.IPLimit.RequestsPerSecond), server.RateLimit.IPLimit.Burst)
+			slog.Info("Rate limits replaced by config file, adjustments made through the admin API are discarded",
+				"requests_per_second", server.RateLimit.RequestsPerSecond, "burst", server.RateLimit.Burst)
+		}
+		proxyService.SetTimeouts(services.NewTimeouts(server))
+		return nil
+	}, hupChan, stopChan)
+
+	// 统计中间件
+	statsMiddleware := func(c *gin.Context) {
+		statsService.IncrementTotal()
+		c.Next()
+		// 流式响应出错时响应头已发送，以记录的实际状态为准
+		status := apierror.Status(c)
+		if c.Request.Context().Err() != nil {
+			// 客户端已断开，单独计数
+			statsService.IncrementCanceled()
+		} else if status >= 400 {
+			statsService.IncrementFailed()
+			statsService.IncrementErrorStatus(status)
+		} else {
+			statsService.IncrementSuccess()
+		}
+		switch c.GetString(cache.StatusKey) {
+		case cache.StatusHit:
+			statsService.IncrementCacheHit()
+		case cache.StatusSemanticHit:
+			statsService.IncrementSemanticHit()
+		case cache.StatusMiss:
+			statsService.IncrementCacheMiss()
+		}
+		if c.GetBool(services.CoalescedKey) {
+			statsService.IncrementCoalesced()
+		}
+		// 记录请求和响应大小（分块传输的请求长度未知）
+		if c.Request.ContentLength > 0 {
 			statsService.AddBytesReceived(uint64(c.Request.ContentLength))
-			statsService.AddBytesSent(uint64(c.Writer.Size()))
-		})
+		}
+		statsService.AddBytesSent(uint64(c.Writer.Size()))
+	}
+
+	// 限流（在认证之前）和认证中间件，所有监听共用限流器和密钥环缓存
+	rateLimit := middleware.RateLimit(globalLimiter, ipLimiter)
+	tokenAuth := middleware.TokenAuth(cfg)
+
+	// 管理接口处理器
+	var adminHandler *handlers.AdminHandler
+	var tokenHandler *handlers.TokenHandler
+	var adminAuth gin.HandlerFunc
+	if cfg.Server.Admin.Enabled {
+		adminHandler = handlers.NewAdminHandler(cfg, globalLimiter, ipLimiter)
+		tokenHandler = handlers.NewTokenHandler(cfg)
+		adminAuth = middleware.AdminAuth(cfg.Server.Admin.Token)
+	}
+
+	// newRouter 创建只提供监听配置的路由组的路由器
+	newRouter := func(l config.Listener) *gin.Engine {
+		router := gin.New()
+		router.Use(commonMiddleware...)
 
-		// 添加限流中间件（在认证之前）
-		api.Use(middleware.RateLimit(globalLimiter, ipLimiter))
+		// 健康检查路由
+		if l.HasRoute(config.RouteHealth) {
+			router.GET("/health", healthHandler)
+		}
 
-		// 添加认证中间件
-		api.Use(middleware.TokenAuth(cfg))
+		// 管理接口路由组
+		if adminHandler != nil && l.HasRoute(config.RouteAdmin) {
+			admin := router.Group("/admin")
+			admin.Use(adminAuth)
+			adminHandler.RegisterRoutes(admin)
 
-		// 所有 API 请求通过统一入口处理
-		api.Any("/*path", apiHandler.HandleRequest)
+			// 服务端签发令牌，任何语言的后端都可以通过 HTTP 调用
+			router.POST("/relayapi-admin/tokens", adminAuth, tokenHandler.Mint)
+			router.POST("/relayapi-admin/tokens/inspect", adminAuth, tokenHandler.Inspect)
+		}
+
+		// API 路由组
+		if l.HasRoute(config.RouteAPI) {
+			api := router.Group("/relayapi")
+			api.Use(statsMiddleware, rateLimit, tokenAuth)
+
+			// 所有 API 请求通过统一入口处理
+			api.Any("/*path", apiHandler.HandleRequest)
+		}
+		return router
 	}
 
-	// 启动服务器
-	server := &http.Server{
-		Addr:           serverAddr,
-		Handler:        router,
-		ReadTimeout:    time.Duration(cfg.Server.Server.ReadTimeout) * time.Second,
-		WriteTimeout:   time.Duration(cfg.Server.Server.WriteTimeout) * time.Second,
-		MaxHeaderBytes: cfg.Server.Server.MaxHeaderBytes,
+	// 配置 TLS：证书文件或 ACME 自动证书，可选的 HTTP 重定向监听
+	var tlsSetup *certs.Setup
+	var redirectServer *http.Server
+	if cfg.Server.TLS.Enabled {
+		tlsSetup, err = certs.New(&cfg.Server)
+		if err != nil {
+			fatal("Failed to configure TLS", err)
+		}
+		go tlsSetup.Watch(stopChan)
 	}
 
-	// 在新的 goroutine 中启动服务器
-	go func() {
-		log.Printf("🚀 Server starting on %s", serverAddr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("❌ Failed to start server: %v", err)
+	// 打开所有监听，每个监听使用独立的服务器和路由器
+	servers := make([]*http.Server, len(listeners))
+	addrs := make([]net.Addr, len(listeners))
+	for i, l := range listeners {
+		ln, err := listen.Listen(l, activation)
+		if err != nil {
+			fatal("Failed to listen", err, "listener", l.Name)
 		}
-	}()
+		addrs[i] = ln.Addr()
+		server := &http.Server{
+			Handler:        newRouter(l),
+			ReadTimeout:    time.Duration(cfg.Server.Server.ReadTimeout) * time.Second,
+			WriteTimeout:   time.Duration(cfg.Server.Server.WriteTimeout) * time.Second,
+			MaxHeaderBytes: cfg.Server.Server.MaxHeaderBytes,
+		}
+		if l.TLS {
+			tlsSetup.Configure(server, cfg.Server.TLS.DisableHTTP2)
+		}
+		servers[i] = server
+
+		// 在新的 goroutine 中启动服务器
+		go func(l config.Listener, ln net.Listener) {
+			useTLS := server.TLSConfig != nil
+			slog.Info("Listener starting", "listener", l.Name, "address", ln.Addr().String(),
+				"tls", useTLS, "routes", listenerRoutes(l))
+			var err error
+			if useTLS {
+				err = server.ServeTLS(ln, "", "")
+			} else {
+				err = server.Serve(ln)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				fatal("Failed to serve", err, "listener", l.Name)
+			}
+		}(l, ln)
+	}
+	// 关闭没有被任何监听使用的 systemd 套接字
+	activation.Close()
+
+	if cfg.Server.TLS.Enabled && cfg.Server.TLS.RedirectHTTP != "" {
+		// 重定向到实际提供 api 路由的 TLS 监听的端口，包括 systemd 传入的套接字
+		redirectPort, err := config.RedirectPortOf(listeners, addrs)
+		if err != nil {
+			fatal("Failed to configure HTTP redirect", err)
+		}
+		redirectServer = &http.Server{
+			Addr:              cfg.Server.TLS.RedirectHTTP,
+			Handler:           tlsSetup.HTTPHandler(certs.RedirectHandler(redirectPort)),
+			ReadHeaderTimeout: time.Duration(cfg.Server.Server.ReadTimeout) * time.Second,
+		}
+	}
+
+	if redirectServer != nil {
+		go func() {
+			slog.Info("HTTP redirect starting", "address", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Failed to start HTTP redirect server", err)
+			}
+		}()
+	}
 
 	// 等待中断信号
 	<-sigChan
-	log.Println("\n⚡ Shutting down server...")
+	shutdownTimeout := cfg.Server.ShutdownTimeout()
+	slog.Info("Shutting down server", "active_requests", drainer.Active(), "timeout", shutdownTimeout.String())
 
-	// 关闭统计显示
+	// 1. 拒绝新请求，停止统计显示和配置监控
+	drainer.Start()
 	close(stopChan)
 
-	// 优雅关闭服务器
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	// 2. 停止接受连接并等待进行中的请求和流式响应完成，再次收到信号时立即结束等待
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
-		fmt.Printf("❌ Server forced to shutdown: %v", err)
+	go func() {
+		select {
+		case <-sigChan:
+			slog.Warn("Received second signal, closing active connections")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	shutdown := func(name string, srv *http.Server) {
+		if err := srv.Shutdown(ctx); err != nil {
+			printStatus(slog.LevelError, fmt.Sprintf("❌ %s did not drain in time, closing active connections: %v", name, err),
+				"Listener did not drain in time, closing active connections", "listener", name, "error", err)
+			srv.Close()
+		}
+	}
+	for i, server := range servers {
+		shutdown(listeners[i].Name, server)
 	}
+	if redirectServer != nil {
+		shutdown("HTTP redirect server", redirectServer)
+	}
+
+	// 3. 等待处理器写完响应日志，与关闭监听共用 shutdown_timeout 的剩余时间，再次收到信号时不再等待
+	if err := drainer.Wait(ctx); err != nil {
+		printStatus(slog.LevelError, fmt.Sprintf("❌ %d requests still running after shutdown", drainer.Active()),
+			"Requests still running after shutdown", "active_requests", drainer.Active())
+	}
+
+	// 4. 写完缓冲中的日志并关闭所有日志写入器（Parquet 文件在关闭时写入文件尾）
+	logWriters.Close()
+
+	// 5. 关闭响应缓存
+	if responseCache != nil {
+		if err := responseCache.Close(); err != nil {
+			printStatus(slog.LevelError, fmt.Sprintf("❌ Failed to close response cache: %v", err),
+				"Failed to close response cache", "error", err)
+		}
+	}
+
+	printStatus(slog.LevelInfo, "✅ Server stopped gracefully", "Server stopped gracefully")
+}
 
-	fmt.Println("✅ Server stopped gracefully")
+// printTitle 打印渐变色的启动标题
+func printTitle() {
+	// 渐变色数组
+	gradientColors := []string{
+		"\033[38;5;51m", // 浅青色
+		"\033[38;5;45m", // 青色
+		"\033[38;5;39m", // 深青色
+		"\033[38;5;33m", // 蓝色
+		"\033[38;5;27m", // 深蓝色
+	}
+
+	title := "=== RelayAPI Server Starting ==="
+	colorIdx := 0
+	for _, char := range title {
+		fmt.Print(gradientColors[colorIdx%len(gradientColors)], string(char))
+		colorIdx++
+	}
+	fmt.Print("\033[0m\n\n")
+}
+
+// fatal 记录错误日志后退出
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append(args, "error", err)...)
+	if !headlessMode {
+		// 非调试模式下日志被丢弃，错误同时打印到标准错误
+		fmt.Fprintf(os.Stderr, "❌ %s: %v\n", msg, err)
+	}
+	os.Exit(1)
+}
+
+// printStatus 输出关闭过程中的状态：终端模式下直接打印（统计界面已关闭），无界面模式下写入结构化日志
+func printStatus(level slog.Level, text string, msg string, args ...any) {
+	if headlessMode {
+		slog.Log(context.Background(), level, msg, args...)
+		return
+	}
+	fmt.Println(text)
+}
+
+// listenerAddrs 返回统计面板中显示的监听地址
+func listenerAddrs(listeners []config.Listener) string {
+	addrs := make([]string, len(listeners))
+	for i, l := range listeners {
+		addrs[i] = l.Address
+		if l.Network != config.NetworkTCP {
+			addrs[i] = strings.TrimSuffix(l.Network+":"+l.Address, ":")
+		}
+	}
+	return strings.Join(addrs, ", ")
+}
+
+// listenerRoutes 返回监听提供的路由组
+func listenerRoutes(l config.Listener) string {
+	if len(l.Routes) == 0 {
+		return "all"
+	}
+	return strings.Join(l.Routes, ", ")
 }
 
 // isFlagPassed 检查命令行参数是否被传递
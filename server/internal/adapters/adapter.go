@@ -0,0 +1,283 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Request 转换后的上游请求
+type Request struct {
+	Path         string // 相对于提供商基础 URL 的路径
+	Body         []byte // 转换后的请求体
+	Model        string // 原始请求中的模型名称
+	Stream       bool   // 是否为流式请求
+	IncludeUsage bool   // 流式响应是否需要返回 usage
+}
+
+// StreamConverter 将上游 SSE 事件的 data 内容转换为 OpenAI chunk
+type StreamConverter interface {
+	// Convert 转换一条 data 内容，返回需要以 "data: " 形式写出的负载
+	Convert(data []byte) ([][]byte, error)
+	// Finish 在上游流结束时调用，返回需要补充写出的负载
+	Finish() ([][]byte, error)
+}
+
+// Adapter 在 OpenAI chat-completions 格式与其他提供商格式之间转换
+type Adapter interface {
+	// Supports 判断该路径是否需要转换
+	Supports(path string) bool
+	// ConvertRequest 将 OpenAI 请求体转换为上游请求
+	ConvertRequest(body []byte) (*Request, error)
+	// SetAuthHeaders 设置上游所需的认证头
+	SetAuthHeaders(headers http.Header, apiKey string)
+	// ConvertResponse 将上游的非流式响应转换为 OpenAI 格式
+	ConvertResponse(req *Request, body []byte) ([]byte, error)
+	// ConvertError 将上游的错误响应转换为 OpenAI 错误格式
+	ConvertError(body []byte) []byte
+	// NewStreamConverter 创建流式响应转换器
+	NewStreamConverter(req *Request) StreamConverter
+}
+
+// registry 已注册的适配器
+var registry = map[string]Adapter{
+	"anthropic": &AnthropicAdapter{},
+	"gemini":    &GeminiAdapter{},
+}
+
+// ProviderAdapters 默认启用转换的提供商
+var ProviderAdapters = map[string]string{
+	"anthropic-openai": "anthropic",
+	"googleai-openai":  "gemini",
+}
+
+// Get 根据名称获取适配器
+func Get(name string) (Adapter, bool) {
+	adapter, ok := registry[name]
+	return adapter, ok
+}
+
+// ForProvider 获取提供商默认的适配器
+func ForProvider(provider string) (Adapter, bool) {
+	name, ok := ProviderAdapters[provider]
+	if !ok {
+		return nil, false
+	}
+	return Get(name)
+}
+
+// isChatCompletionsPath 判断是否为 chat/completions 路径
+func isChatCompletionsPath(path string) bool {
+	path = strings.Trim(path, "/")
+	path = strings.TrimPrefix(path, "v1/")
+	return path == "chat/completions"
+}
+
+// openAIRequest OpenAI chat-completions 请求
+type openAIRequest struct {
+	Model               string          `json:"model"`
+	Messages            []openAIMessage `json:"messages"`
+	MaxTokens           *int            `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64        `json:"temperature,omitempty"`
+	TopP                *float64        `json:"top_p,omitempty"`
+	N                   *int            `json:"n,omitempty"`
+	Stop                json.RawMessage `json:"stop,omitempty"`
+	Stream              bool            `json:"stream,omitempty"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+	ToolChoice     json.RawMessage       `json:"tool_choice,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	User           string                `json:"user,omitempty"`
+}
+
+// openAIMessage OpenAI 消息
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    json.RawMessage  `json:"content,omitempty"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIContentPart OpenAI 多模态消息片段
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL    string `json:"url"`
+		Detail string `json:"detail,omitempty"`
+	} `json:"image_url,omitempty"`
+}
+
+// openAIToolCall OpenAI 工具调用
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAITool OpenAI 工具定义
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+// openAIResponseFormat OpenAI 响应格式
+type openAIResponseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Name   string          `json:"name"`
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema,omitempty"`
+}
+
+// openAIResponse OpenAI 非流式响应
+type openAIResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage,omitempty"`
+}
+
+// openAIChoice OpenAI 非流式响应选项
+type openAIChoice struct {
+	Index        int                   `json:"index"`
+	Message      openAIResponseMessage `json:"message"`
+	FinishReason string                `json:"finish_reason"`
+}
+
+// openAIResponseMessage OpenAI 响应消息
+type openAIResponseMessage struct {
+	Role      string           `json:"role"`
+	Content   *string          `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+// openAIChunk OpenAI 流式响应块
+type openAIChunk struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []openAIChunkChoice `json:"choices"`
+	Usage   *openAIUsage        `json:"usage,omitempty"`
+}
+
+// openAIChunkChoice OpenAI 流式响应选项
+type openAIChunkChoice struct {
+	Index        int         `json:"index"`
+	Delta        openAIDelta `json:"delta"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+// openAIDelta OpenAI 流式增量
+type openAIDelta struct {
+	Role      string           `json:"role,omitempty"`
+	Content   *string          `json:"content,omitempty"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+// openAIUsage OpenAI 用量统计
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// openAIError OpenAI 错误格式
+type openAIError struct {
+	Error struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Param   interface{} `json:"param"`
+		Code    interface{} `json:"code"`
+	} `json:"error"`
+}
+
+// parseContent 解析字符串或数组形式的消息内容
+func parseContent(raw json.RawMessage) ([]openAIContentPart, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []openAIContentPart{{Type: "text", Text: text}}, nil
+	}
+	var parts []openAIContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return nil, fmt.Errorf("invalid message content: %v", err)
+	}
+	return parts, nil
+}
+
+// contentText 拼接消息内容中的文本部分
+func contentText(raw json.RawMessage) (string, error) {
+	parts, err := parseContent(raw)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// parseStop 解析字符串或数组形式的 stop 参数
+func parseStop(raw json.RawMessage) []string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		if single == "" {
+			return nil
+		}
+		return []string{single}
+	}
+	var list []string
+	json.Unmarshal(raw, &list)
+	return list
+}
+
+// parseDataURL 解析 data:image/png;base64,xxx 格式的图片地址
+func parseDataURL(url string) (mediaType, data string, ok bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", "", false
+	}
+	header, data, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !found || !strings.HasSuffix(header, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(header, ";base64"), data, true
+}
+
+// newOpenAIError 构造 OpenAI 错误响应
+func newOpenAIError(errType, message string) []byte {
+	var e openAIError
+	e.Error.Type = errType
+	e.Error.Message = message
+	data, _ := json.Marshal(e)
+	return data
+}
+
+// stringPtr 返回字符串指针
+func stringPtr(s string) *string {
+	return &s
+}
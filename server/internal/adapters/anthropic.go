@@ -0,0 +1,510 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// anthropicVersion Anthropic API 版本
+	anthropicVersion = "2023-06-01"
+	// anthropicDefaultMaxTokens 请求未指定 max_tokens 时的默认值
+	anthropicDefaultMaxTokens = 4096
+)
+
+// AnthropicAdapter 将 OpenAI chat-completions 转换为 Anthropic Messages API
+type AnthropicAdapter struct{}
+
+// anthropicRequest Anthropic Messages 请求
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice    *anthropicChoice   `json:"tool_choice,omitempty"`
+	Metadata      *struct {
+		UserID string `json:"user_id,omitempty"`
+	} `json:"metadata,omitempty"`
+}
+
+// anthropicMessage Anthropic 消息
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock Anthropic 内容块
+type anthropicBlock struct {
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	Source    *anthropicSource `json:"source,omitempty"`
+	ID        string           `json:"id,omitempty"`
+	Name      string           `json:"name,omitempty"`
+	Input     json.RawMessage  `json:"input,omitempty"`
+	ToolUseID string           `json:"tool_use_id,omitempty"`
+	Content   string           `json:"content,omitempty"`
+}
+
+// anthropicSource Anthropic 图片来源
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicTool Anthropic 工具定义
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicChoice Anthropic 工具选择
+type anthropicChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// anthropicResponse Anthropic Messages 响应
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// anthropicUsage Anthropic 用量统计
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicError Anthropic 错误格式
+type anthropicError struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Supports 只转换 chat/completions 请求
+func (a *AnthropicAdapter) Supports(path string) bool {
+	return isChatCompletionsPath(path)
+}
+
+// ConvertRequest 将 OpenAI 请求转换为 Anthropic Messages 请求
+func (a *AnthropicAdapter) ConvertRequest(body []byte) (*Request, error) {
+	var in openAIRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid chat completions request: %v", err)
+	}
+
+	out := anthropicRequest{
+		Model:         in.Model,
+		MaxTokens:     anthropicDefaultMaxTokens,
+		Temperature:   in.Temperature,
+		TopP:          in.TopP,
+		StopSequences: parseStop(in.Stop),
+		Stream:        in.Stream,
+	}
+	if in.MaxCompletionTokens != nil {
+		out.MaxTokens = *in.MaxCompletionTokens
+	} else if in.MaxTokens != nil {
+		out.MaxTokens = *in.MaxTokens
+	}
+	if in.User != "" {
+		out.Metadata = &struct {
+			UserID string `json:"user_id,omitempty"`
+		}{UserID: in.User}
+	}
+
+	// 转换消息，system/developer 消息合并为 system 字段
+	var systemParts []string
+	for _, msg := range in.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			text, err := contentText(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			systemParts = append(systemParts, text)
+		case "user":
+			blocks, err := anthropicContentBlocks(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			out.Messages = appendAnthropicMessage(out.Messages, "user", blocks)
+		case "assistant":
+			blocks, err := anthropicContentBlocks(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if len(strings.TrimSpace(call.Function.Arguments)) == 0 {
+					input = json.RawMessage("{}")
+				} else if !json.Valid(input) {
+					return nil, fmt.Errorf("invalid arguments for tool call %s", call.ID)
+				}
+				blocks = append(blocks, anthropicBlock{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Function.Name,
+					Input: input,
+				})
+			}
+			out.Messages = appendAnthropicMessage(out.Messages, "assistant", blocks)
+		case "tool":
+			text, err := contentText(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			out.Messages = appendAnthropicMessage(out.Messages, "user", []anthropicBlock{{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   text,
+			}})
+		default:
+			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+	}
+	out.System = strings.Join(systemParts, "\n\n")
+
+	// 转换工具定义
+	for _, tool := range in.Tools {
+		schema := tool.Function.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		out.Tools = append(out.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	choice, disableTools := anthropicToolChoice(in.ToolChoice)
+	if disableTools {
+		out.Tools = nil
+	} else if len(out.Tools) > 0 {
+		out.ToolChoice = choice
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{
+		Path:         "messages",
+		Body:         data,
+		Model:        in.Model,
+		Stream:       in.Stream,
+		IncludeUsage: in.StreamOptions != nil && in.StreamOptions.IncludeUsage,
+	}, nil
+}
+
+// SetAuthHeaders Anthropic 使用 x-api-key 认证
+func (a *AnthropicAdapter) SetAuthHeaders(headers http.Header, apiKey string) {
+	headers.Del("Authorization")
+	headers.Set("X-Api-Key", apiKey)
+	headers.Set("Anthropic-Version", anthropicVersion)
+	headers.Set("Content-Type", "application/json")
+}
+
+// ConvertResponse 将 Anthropic 响应转换为 OpenAI chat.completion
+func (a *AnthropicAdapter) ConvertResponse(req *Request, body []byte) ([]byte, error) {
+	var in anthropicResponse
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid anthropic response: %v", err)
+	}
+
+	message := openAIResponseMessage{Role: "assistant"}
+	var texts []string
+	for _, block := range in.Content {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+		case "tool_use":
+			call := openAIToolCall{ID: block.ID, Type: "function"}
+			call.Function.Name = block.Name
+			call.Function.Arguments = string(block.Input)
+			message.ToolCalls = append(message.ToolCalls, call)
+		}
+	}
+	if len(texts) > 0 || len(message.ToolCalls) == 0 {
+		message.Content = stringPtr(strings.Join(texts, ""))
+	}
+
+	model := in.Model
+	if model == "" {
+		model = req.Model
+	}
+	out := openAIResponse{
+		ID:      in.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openAIChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: anthropicFinishReason(in.StopReason),
+		}},
+		Usage: &openAIUsage{
+			PromptTokens:     in.Usage.InputTokens,
+			CompletionTokens: in.Usage.OutputTokens,
+			TotalTokens:      in.Usage.InputTokens + in.Usage.OutputTokens,
+		},
+	}
+	return json.Marshal(out)
+}
+
+// ConvertError 将 Anthropic 错误转换为 OpenAI 错误格式
+func (a *AnthropicAdapter) ConvertError(body []byte) []byte {
+	var in anthropicError
+	if err := json.Unmarshal(body, &in); err != nil || in.Error.Message == "" {
+		return newOpenAIError("upstream_error", strings.TrimSpace(string(body)))
+	}
+	return newOpenAIError(in.Error.Type, in.Error.Message)
+}
+
+// NewStreamConverter 创建 Anthropic SSE 事件转换器
+func (a *AnthropicAdapter) NewStreamConverter(req *Request) StreamConverter {
+	return &anthropicStreamConverter{
+		model:        req.Model,
+		includeUsage: req.IncludeUsage,
+		created:      time.Now().Unix(),
+		toolIndexes:  make(map[int]int),
+	}
+}
+
+// anthropicStreamConverter 将 Anthropic 流式事件转换为 OpenAI chunk
+type anthropicStreamConverter struct {
+	id           string
+	model        string
+	created      int64
+	includeUsage bool
+	usage        anthropicUsage
+	toolIndexes  map[int]int // Anthropic 内容块索引 -> OpenAI tool_calls 索引
+}
+
+// anthropicStreamEvent Anthropic 流式事件
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	ContentBlock *anthropicBlock    `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Convert 转换一条 Anthropic 事件
+func (s *anthropicStreamConverter) Convert(data []byte) ([][]byte, error) {
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("invalid anthropic stream event: %v", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			s.id = event.Message.ID
+			if event.Message.Model != "" {
+				s.model = event.Message.Model
+			}
+			s.usage.InputTokens = event.Message.Usage.InputTokens
+		}
+		return s.chunk(openAIDelta{Role: "assistant", Content: stringPtr("")}, nil)
+
+	case "content_block_start":
+		if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+			return nil, nil
+		}
+		index := len(s.toolIndexes)
+		s.toolIndexes[event.Index] = index
+		call := openAIToolCall{Index: &index, ID: event.ContentBlock.ID, Type: "function"}
+		call.Function.Name = event.ContentBlock.Name
+		return s.chunk(openAIDelta{ToolCalls: []openAIToolCall{call}}, nil)
+
+	case "content_block_delta":
+		if event.Delta == nil {
+			return nil, nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			return s.chunk(openAIDelta{Content: stringPtr(event.Delta.Text)}, nil)
+		case "input_json_delta":
+			index, ok := s.toolIndexes[event.Index]
+			if !ok {
+				return nil, nil
+			}
+			call := openAIToolCall{Index: &index}
+			call.Function.Arguments = event.Delta.PartialJSON
+			return s.chunk(openAIDelta{ToolCalls: []openAIToolCall{call}}, nil)
+		}
+		return nil, nil
+
+	case "message_delta":
+		if event.Usage != nil {
+			s.usage.OutputTokens = event.Usage.OutputTokens
+		}
+		if event.Delta == nil || event.Delta.StopReason == "" {
+			return nil, nil
+		}
+		reason := anthropicFinishReason(event.Delta.StopReason)
+		return s.chunk(openAIDelta{}, &reason)
+
+	case "message_stop":
+		var out [][]byte
+		if s.includeUsage {
+			usage, err := json.Marshal(openAIChunk{
+				ID:      s.id,
+				Object:  "chat.completion.chunk",
+				Created: s.created,
+				Model:   s.model,
+				Choices: []openAIChunkChoice{},
+				Usage: &openAIUsage{
+					PromptTokens:     s.usage.InputTokens,
+					CompletionTokens: s.usage.OutputTokens,
+					TotalTokens:      s.usage.InputTokens + s.usage.OutputTokens,
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, usage)
+		}
+		return append(out, []byte("[DONE]")), nil
+
+	case "error":
+		if event.Error == nil {
+			return nil, nil
+		}
+		return [][]byte{newOpenAIError(event.Error.Type, event.Error.Message)}, nil
+	}
+
+	// ping、content_block_stop 等事件无需转发
+	return nil, nil
+}
+
+// Finish Anthropic 以 message_stop 结束，无需补充
+func (s *anthropicStreamConverter) Finish() ([][]byte, error) {
+	return nil, nil
+}
+
+// chunk 构造单个 OpenAI 流式响应块
+func (s *anthropicStreamConverter) chunk(delta openAIDelta, finishReason *string) ([][]byte, error) {
+	data, err := json.Marshal(openAIChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []openAIChunkChoice{{
+			Index:        0,
+			Delta:        delta,
+			FinishReason: finishReason,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{data}, nil
+}
+
+// anthropicContentBlocks 将 OpenAI 消息内容转换为 Anthropic 内容块
+func anthropicContentBlocks(raw json.RawMessage) ([]anthropicBlock, error) {
+	parts, err := parseContent(raw)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []anthropicBlock
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+			}
+		case "image_url":
+			if part.ImageURL == nil {
+				continue
+			}
+			source := &anthropicSource{Type: "url", URL: part.ImageURL.URL}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				source = &anthropicSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		default:
+			return nil, fmt.Errorf("unsupported content part type: %s", part.Type)
+		}
+	}
+	return blocks, nil
+}
+
+// appendAnthropicMessage 追加消息，连续相同角色的消息会被合并
+func appendAnthropicMessage(messages []anthropicMessage, role string, blocks []anthropicBlock) []anthropicMessage {
+	if len(blocks) == 0 {
+		return messages
+	}
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		messages[n-1].Content = append(messages[n-1].Content, blocks...)
+		return messages
+	}
+	return append(messages, anthropicMessage{Role: role, Content: blocks})
+}
+
+// anthropicToolChoice 转换 tool_choice，第二个返回值表示是否禁用工具
+func anthropicToolChoice(raw json.RawMessage) (*anthropicChoice, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, false
+	}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		switch mode {
+		case "none":
+			return nil, true
+		case "required":
+			return &anthropicChoice{Type: "any"}, false
+		default:
+			return &anthropicChoice{Type: "auto"}, false
+		}
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err == nil && named.Function.Name != "" {
+		return &anthropicChoice{Type: "tool", Name: named.Function.Name}, false
+	}
+	return nil, false
+}
+
+// anthropicFinishReason 将 stop_reason 映射为 OpenAI finish_reason
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
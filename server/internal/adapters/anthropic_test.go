@@ -0,0 +1,143 @@
+package adapters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicConvertRequest(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-3-5-sonnet",
+		"stream": true,
+		"stop": "END",
+		"messages": [
+			{"role": "system", "content": "You are helpful."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is in this image?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": "{\"q\":\"cat\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "a cat"}
+		],
+		"tools": [{"type": "function", "function": {"name": "lookup", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`)
+
+	adapter := &AnthropicAdapter{}
+	req, err := adapter.ConvertRequest(body)
+	if err != nil {
+		t.Fatalf("ConvertRequest failed: %v", err)
+	}
+	if req.Path != "messages" || !req.Stream || req.Model != "claude-3-5-sonnet" {
+		t.Errorf("Unexpected request metadata: %+v", req)
+	}
+
+	var out anthropicRequest
+	if err := json.Unmarshal(req.Body, &out); err != nil {
+		t.Fatalf("Invalid converted body: %v", err)
+	}
+	if out.System != "You are helpful." {
+		t.Errorf("Expected system prompt, got %q", out.System)
+	}
+	if out.MaxTokens != anthropicDefaultMaxTokens {
+		t.Errorf("Expected default max_tokens, got %d", out.MaxTokens)
+	}
+	if len(out.StopSequences) != 1 || out.StopSequences[0] != "END" {
+		t.Errorf("Unexpected stop sequences: %v", out.StopSequences)
+	}
+	if len(out.Messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(out.Messages))
+	}
+	if img := out.Messages[0].Content[1]; img.Type != "image" || img.Source.Type != "base64" || img.Source.MediaType != "image/png" {
+		t.Errorf("Unexpected image block: %+v", img)
+	}
+	if call := out.Messages[1].Content[0]; call.Type != "tool_use" || call.Name != "lookup" {
+		t.Errorf("Unexpected tool_use block: %+v", call)
+	}
+	if result := out.Messages[2].Content[0]; result.Type != "tool_result" || result.ToolUseID != "call_1" {
+		t.Errorf("Unexpected tool_result block: %+v", result)
+	}
+	if out.ToolChoice == nil || out.ToolChoice.Type != "any" {
+		t.Errorf("Expected tool_choice any, got %+v", out.ToolChoice)
+	}
+}
+
+func TestAnthropicConvertResponse(t *testing.T) {
+	body := []byte(`{
+		"id": "msg_1",
+		"model": "claude-3-5-sonnet",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "cat"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`)
+
+	adapter := &AnthropicAdapter{}
+	data, err := adapter.ConvertResponse(&Request{Model: "claude-3-5-sonnet"}, body)
+	if err != nil {
+		t.Fatalf("ConvertResponse failed: %v", err)
+	}
+
+	var out openAIResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Invalid converted response: %v", err)
+	}
+	choice := out.Choices[0]
+	if choice.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish_reason tool_calls, got %s", choice.FinishReason)
+	}
+	if choice.Message.Content == nil || *choice.Message.Content != "Let me check." {
+		t.Errorf("Unexpected content: %v", choice.Message.Content)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"q": "cat"}` {
+		t.Errorf("Unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if out.Usage.TotalTokens != 15 {
+		t.Errorf("Expected 15 total tokens, got %d", out.Usage.TotalTokens)
+	}
+}
+
+func TestAnthropicStreamConverter(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude","usage":{"input_tokens":3}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	converter := (&AnthropicAdapter{}).NewStreamConverter(&Request{Model: "claude", IncludeUsage: true})
+	var payloads []string
+	for _, event := range events {
+		out, err := converter.Convert([]byte(event))
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		for _, payload := range out {
+			payloads = append(payloads, string(payload))
+		}
+	}
+
+	// role 块、内容块、finish 块、usage 块、[DONE]
+	if len(payloads) != 5 {
+		t.Fatalf("Expected 5 payloads, got %d: %v", len(payloads), payloads)
+	}
+	if !strings.Contains(payloads[1], `"content":"Hello"`) {
+		t.Errorf("Unexpected content chunk: %s", payloads[1])
+	}
+	if !strings.Contains(payloads[2], `"finish_reason":"stop"`) {
+		t.Errorf("Unexpected finish chunk: %s", payloads[2])
+	}
+	if !strings.Contains(payloads[3], `"total_tokens":4`) {
+		t.Errorf("Unexpected usage chunk: %s", payloads[3])
+	}
+	if payloads[4] != "[DONE]" {
+		t.Errorf("Expected [DONE], got %s", payloads[4])
+	}
+}
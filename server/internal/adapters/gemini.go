@@ -0,0 +1,597 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// GeminiAdapter 将 OpenAI chat-completions 转换为 Gemini generateContent API
+type GeminiAdapter struct{}
+
+// geminiRequest Gemini generateContent 请求
+type geminiRequest struct {
+	SystemInstruction *geminiContent        `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent       `json:"contents"`
+	Tools             []geminiTool          `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig     `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenerationConf `json:"generationConfig,omitempty"`
+}
+
+// geminiContent Gemini 内容
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart Gemini 内容片段
+type geminiPart struct {
+	Text         string      `json:"text,omitempty"`
+	InlineData   *geminiBlob `json:"inlineData,omitempty"`
+	FileData     *geminiFile `json:"fileData,omitempty"`
+	FunctionCall *struct {
+		Name string          `json:"name"`
+		Args json.RawMessage `json:"args,omitempty"`
+	} `json:"functionCall,omitempty"`
+	FunctionResponse *struct {
+		Name     string          `json:"name"`
+		Response json.RawMessage `json:"response"`
+	} `json:"functionResponse,omitempty"`
+}
+
+// geminiBlob Gemini 内联数据
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// geminiFile Gemini 文件引用
+type geminiFile struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+// geminiTool Gemini 工具定义
+type geminiTool struct {
+	FunctionDeclarations []geminiFunction `json:"functionDeclarations"`
+}
+
+// geminiFunction Gemini 函数声明
+type geminiFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// geminiToolConfig Gemini 工具调用配置
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"`
+		AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+	} `json:"functionCallingConfig"`
+}
+
+// geminiGenerationConf Gemini 生成参数
+type geminiGenerationConf struct {
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"topP,omitempty"`
+	MaxOutputTokens  *int            `json:"maxOutputTokens,omitempty"`
+	CandidateCount   *int            `json:"candidateCount,omitempty"`
+	StopSequences    []string        `json:"stopSequences,omitempty"`
+	ResponseMimeType string          `json:"responseMimeType,omitempty"`
+	ResponseSchema   json.RawMessage `json:"responseSchema,omitempty"`
+}
+
+// geminiResponse Gemini generateContent 响应
+type geminiResponse struct {
+	Candidates []struct {
+		Index        int           `json:"index"`
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	ModelVersion string `json:"modelVersion,omitempty"`
+	ResponseID   string `json:"responseId,omitempty"`
+}
+
+// geminiError Gemini 错误格式
+type geminiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// Supports 只转换 chat/completions 请求
+func (a *GeminiAdapter) Supports(path string) bool {
+	return isChatCompletionsPath(path)
+}
+
+// ConvertRequest 将 OpenAI 请求转换为 Gemini generateContent 请求
+func (a *GeminiAdapter) ConvertRequest(body []byte) (*Request, error) {
+	var in openAIRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid chat completions request: %v", err)
+	}
+	if in.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+
+	out := geminiRequest{}
+
+	// 记录工具调用 ID 对应的函数名，Gemini 的 functionResponse 需要函数名
+	toolNames := make(map[string]string)
+	var systemParts []geminiPart
+	for _, msg := range in.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			text, err := contentText(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			systemParts = append(systemParts, geminiPart{Text: text})
+		case "user":
+			parts, err := geminiParts(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			out.Contents = appendGeminiContent(out.Contents, "user", parts)
+		case "assistant":
+			parts, err := geminiParts(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			for _, call := range msg.ToolCalls {
+				args := json.RawMessage(call.Function.Arguments)
+				if len(strings.TrimSpace(call.Function.Arguments)) == 0 {
+					args = json.RawMessage("{}")
+				} else if !json.Valid(args) {
+					return nil, fmt.Errorf("invalid arguments for tool call %s", call.ID)
+				}
+				toolNames[call.ID] = call.Function.Name
+				part := geminiPart{}
+				part.FunctionCall = &struct {
+					Name string          `json:"name"`
+					Args json.RawMessage `json:"args,omitempty"`
+				}{Name: call.Function.Name, Args: args}
+				parts = append(parts, part)
+			}
+			out.Contents = appendGeminiContent(out.Contents, "model", parts)
+		case "tool":
+			text, err := contentText(msg.Content)
+			if err != nil {
+				return nil, err
+			}
+			name := toolNames[msg.ToolCallID]
+			if name == "" {
+				name = msg.Name
+			}
+			part := geminiPart{}
+			part.FunctionResponse = &struct {
+				Name     string          `json:"name"`
+				Response json.RawMessage `json:"response"`
+			}{Name: name, Response: geminiFunctionResponse(text)}
+			out.Contents = appendGeminiContent(out.Contents, "user", []geminiPart{part})
+		default:
+			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+	}
+	if len(systemParts) > 0 {
+		out.SystemInstruction = &geminiContent{Parts: systemParts}
+	}
+
+	// 转换工具定义
+	if len(in.Tools) > 0 {
+		tool := geminiTool{}
+		for _, t := range in.Tools {
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, geminiFunction{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  cleanGeminiSchema(t.Function.Parameters),
+			})
+		}
+		out.Tools = []geminiTool{tool}
+		out.ToolConfig = geminiToolChoice(in.ToolChoice)
+	}
+
+	// 转换生成参数
+	gen := &geminiGenerationConf{
+		Temperature:    in.Temperature,
+		TopP:           in.TopP,
+		CandidateCount: in.N,
+		StopSequences:  parseStop(in.Stop),
+	}
+	if in.MaxCompletionTokens != nil {
+		gen.MaxOutputTokens = in.MaxCompletionTokens
+	} else {
+		gen.MaxOutputTokens = in.MaxTokens
+	}
+	if in.ResponseFormat != nil {
+		switch in.ResponseFormat.Type {
+		case "json_object":
+			gen.ResponseMimeType = "application/json"
+		case "json_schema":
+			gen.ResponseMimeType = "application/json"
+			if in.ResponseFormat.JSONSchema != nil {
+				gen.ResponseSchema = cleanGeminiSchema(in.ResponseFormat.JSONSchema.Schema)
+			}
+		}
+	}
+	out.GenerationConfig = gen
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+
+	model := url.PathEscape(strings.TrimPrefix(in.Model, "models/"))
+	targetPath := fmt.Sprintf("models/%s:generateContent", model)
+	if in.Stream {
+		targetPath = fmt.Sprintf("models/%s:streamGenerateContent?alt=sse", model)
+	}
+	return &Request{
+		Path:         targetPath,
+		Body:         data,
+		Model:        in.Model,
+		Stream:       in.Stream,
+		IncludeUsage: in.StreamOptions != nil && in.StreamOptions.IncludeUsage,
+	}, nil
+}
+
+// SetAuthHeaders Gemini 使用 x-goog-api-key 认证
+func (a *GeminiAdapter) SetAuthHeaders(headers http.Header, apiKey string) {
+	headers.Del("Authorization")
+	headers.Set("X-Goog-Api-Key", apiKey)
+	headers.Set("Content-Type", "application/json")
+}
+
+// ConvertResponse 将 Gemini 响应转换为 OpenAI chat.completion
+func (a *GeminiAdapter) ConvertResponse(req *Request, body []byte) ([]byte, error) {
+	var in geminiResponse
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid gemini response: %v", err)
+	}
+
+	out := openAIResponse{
+		ID:      geminiResponseID(in.ResponseID),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []openAIChoice{},
+	}
+	for _, candidate := range in.Candidates {
+		message := openAIResponseMessage{Role: "assistant"}
+		var texts []string
+		for _, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil {
+				message.ToolCalls = append(message.ToolCalls, geminiToolCall(part, len(message.ToolCalls), false))
+				continue
+			}
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		if len(texts) > 0 || len(message.ToolCalls) == 0 {
+			message.Content = stringPtr(strings.Join(texts, ""))
+		}
+		out.Choices = append(out.Choices, openAIChoice{
+			Index:        candidate.Index,
+			Message:      message,
+			FinishReason: geminiFinishReason(candidate.FinishReason, len(message.ToolCalls) > 0),
+		})
+	}
+	if in.UsageMetadata != nil {
+		out.Usage = &openAIUsage{
+			PromptTokens:     in.UsageMetadata.PromptTokenCount,
+			CompletionTokens: in.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      in.UsageMetadata.TotalTokenCount,
+		}
+	}
+	return json.Marshal(out)
+}
+
+// ConvertError 将 Gemini 错误转换为 OpenAI 错误格式
+func (a *GeminiAdapter) ConvertError(body []byte) []byte {
+	var in geminiError
+	if err := json.Unmarshal(body, &in); err != nil || in.Error.Message == "" {
+		return newOpenAIError("upstream_error", strings.TrimSpace(string(body)))
+	}
+	return newOpenAIError(strings.ToLower(in.Error.Status), in.Error.Message)
+}
+
+// NewStreamConverter 创建 Gemini SSE 事件转换器
+func (a *GeminiAdapter) NewStreamConverter(req *Request) StreamConverter {
+	return &geminiStreamConverter{
+		model:        req.Model,
+		includeUsage: req.IncludeUsage,
+		created:      time.Now().Unix(),
+		toolCalls:    make(map[int]int),
+	}
+}
+
+// geminiStreamConverter 将 Gemini 流式响应转换为 OpenAI chunk
+type geminiStreamConverter struct {
+	id           string
+	model        string
+	created      int64
+	includeUsage bool
+	started      bool
+	toolCalls    map[int]int // 每个候选已转换的工具调用数量
+	usage        *openAIUsage
+}
+
+// Convert 转换一条 Gemini 流式响应
+func (s *geminiStreamConverter) Convert(data []byte) ([][]byte, error) {
+	var in geminiResponse
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid gemini stream event: %v", err)
+	}
+	if s.id == "" {
+		s.id = geminiResponseID(in.ResponseID)
+	}
+	if in.UsageMetadata != nil {
+		s.usage = &openAIUsage{
+			PromptTokens:     in.UsageMetadata.PromptTokenCount,
+			CompletionTokens: in.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      in.UsageMetadata.TotalTokenCount,
+		}
+	}
+
+	var choices []openAIChunkChoice
+	for _, candidate := range in.Candidates {
+		delta := openAIDelta{}
+		if !s.started {
+			delta.Role = "assistant"
+		}
+		var texts []string
+		for _, part := range candidate.Content.Parts {
+			if part.FunctionCall != nil {
+				delta.ToolCalls = append(delta.ToolCalls, geminiToolCall(part, s.toolCalls[candidate.Index], true))
+				s.toolCalls[candidate.Index]++
+				continue
+			}
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		if len(texts) > 0 {
+			delta.Content = stringPtr(strings.Join(texts, ""))
+		}
+
+		var finishReason *string
+		if candidate.FinishReason != "" {
+			reason := geminiFinishReason(candidate.FinishReason, s.toolCalls[candidate.Index] > 0)
+			finishReason = &reason
+		}
+		choices = append(choices, openAIChunkChoice{
+			Index:        candidate.Index,
+			Delta:        delta,
+			FinishReason: finishReason,
+		})
+	}
+	if len(choices) == 0 {
+		return nil, nil
+	}
+	s.started = true
+
+	chunk, err := json.Marshal(openAIChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: choices,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{chunk}, nil
+}
+
+// Finish Gemini 流没有结束事件，在 EOF 时补充 usage 和 [DONE]
+func (s *geminiStreamConverter) Finish() ([][]byte, error) {
+	var out [][]byte
+	if s.includeUsage && s.usage != nil {
+		usage, err := json.Marshal(openAIChunk{
+			ID:      s.id,
+			Object:  "chat.completion.chunk",
+			Created: s.created,
+			Model:   s.model,
+			Choices: []openAIChunkChoice{},
+			Usage:   s.usage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, usage)
+	}
+	return append(out, []byte("[DONE]")), nil
+}
+
+// geminiParts 将 OpenAI 消息内容转换为 Gemini 内容片段
+func geminiParts(raw json.RawMessage) ([]geminiPart, error) {
+	parts, err := parseContent(raw)
+	if err != nil {
+		return nil, err
+	}
+	var out []geminiPart
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				out = append(out, geminiPart{Text: part.Text})
+			}
+		case "image_url":
+			if part.ImageURL == nil {
+				continue
+			}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				out = append(out, geminiPart{InlineData: &geminiBlob{MimeType: mediaType, Data: data}})
+				continue
+			}
+			out = append(out, geminiPart{FileData: &geminiFile{
+				MimeType: guessImageMimeType(part.ImageURL.URL),
+				FileURI:  part.ImageURL.URL,
+			}})
+		default:
+			return nil, fmt.Errorf("unsupported content part type: %s", part.Type)
+		}
+	}
+	return out, nil
+}
+
+// appendGeminiContent 追加内容，连续相同角色的内容会被合并
+func appendGeminiContent(contents []geminiContent, role string, parts []geminiPart) []geminiContent {
+	if len(parts) == 0 {
+		return contents
+	}
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, geminiContent{Role: role, Parts: parts})
+}
+
+// geminiFunctionResponse functionResponse 的 response 必须是对象
+func geminiFunctionResponse(text string) json.RawMessage {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	data, _ := json.Marshal(map[string]string{"content": text})
+	return data
+}
+
+// geminiToolChoice 转换 tool_choice
+func geminiToolChoice(raw json.RawMessage) *geminiToolConfig {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	config := &geminiToolConfig{}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		switch mode {
+		case "none":
+			config.FunctionCallingConfig.Mode = "NONE"
+		case "required":
+			config.FunctionCallingConfig.Mode = "ANY"
+		default:
+			config.FunctionCallingConfig.Mode = "AUTO"
+		}
+		return config
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err == nil && named.Function.Name != "" {
+		config.FunctionCallingConfig.Mode = "ANY"
+		config.FunctionCallingConfig.AllowedFunctionNames = []string{named.Function.Name}
+		return config
+	}
+	return nil
+}
+
+// geminiToolCall 将 functionCall 转换为 OpenAI 工具调用。index 为该调用在候选的全部工具调用中的序号，
+// 流式和非流式响应使用相同的 ID
+func geminiToolCall(part geminiPart, index int, withIndex bool) openAIToolCall {
+	call := openAIToolCall{
+		ID:   fmt.Sprintf("call_%d", index),
+		Type: "function",
+	}
+	if withIndex {
+		call.Index = &index
+	}
+	call.Function.Name = part.FunctionCall.Name
+	call.Function.Arguments = "{}"
+	if len(part.FunctionCall.Args) > 0 {
+		call.Function.Arguments = string(part.FunctionCall.Args)
+	}
+	return call
+}
+
+// geminiFinishReason 将 finishReason 映射为 OpenAI finish_reason
+func geminiFinishReason(reason string, hasToolCalls bool) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "content_filter"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// geminiResponseID 生成 OpenAI 风格的响应 ID
+func geminiResponseID(id string) string {
+	if id == "" {
+		id = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return "chatcmpl-" + id
+}
+
+// cleanGeminiSchema 移除 Gemini 不支持的 JSON Schema 关键字
+func cleanGeminiSchema(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var schema interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return raw
+	}
+	data, err := json.Marshal(stripSchemaKeys(schema))
+	if err != nil {
+		return raw
+	}
+	return data
+}
+
+// stripSchemaKeys 递归移除不支持的关键字
+func stripSchemaKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		delete(t, "$schema")
+		delete(t, "additionalProperties")
+		delete(t, "strict")
+		for key, value := range t {
+			// properties 的键是字段名，只处理其中的子 schema
+			if props, ok := value.(map[string]interface{}); ok && key == "properties" {
+				for name, prop := range props {
+					props[name] = stripSchemaKeys(prop)
+				}
+				continue
+			}
+			t[key] = stripSchemaKeys(value)
+		}
+		return t
+	case []interface{}:
+		for i, value := range t {
+			t[i] = stripSchemaKeys(value)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// guessImageMimeType 根据 URL 扩展名推断图片类型
+func guessImageMimeType(imageURL string) string {
+	if u, err := url.Parse(imageURL); err == nil {
+		if mediaType := mime.TypeByExtension(path.Ext(u.Path)); mediaType != "" {
+			return mediaType
+		}
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,152 @@
+package adapters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGeminiConvertRequest(t *testing.T) {
+	body := []byte(`{
+		"model": "gemini-1.5-pro",
+		"stream": true,
+		"max_tokens": 256,
+		"messages": [
+			{"role": "system", "content": "Answer in JSON."},
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}
+		],
+		"tools": [{"type": "function", "function": {"name": "weather", "parameters": {
+			"type": "object", "additionalProperties": false,
+			"properties": {"strict": {"type": "string"}}
+		}}}],
+		"response_format": {"type": "json_object"}
+	}`)
+
+	req, err := (&GeminiAdapter{}).ConvertRequest(body)
+	if err != nil {
+		t.Fatalf("ConvertRequest failed: %v", err)
+	}
+	if req.Path != "models/gemini-1.5-pro:streamGenerateContent?alt=sse" {
+		t.Errorf("Unexpected path: %s", req.Path)
+	}
+
+	var out geminiRequest
+	if err := json.Unmarshal(req.Body, &out); err != nil {
+		t.Fatalf("Invalid converted body: %v", err)
+	}
+	if out.SystemInstruction == nil || out.SystemInstruction.Parts[0].Text != "Answer in JSON." {
+		t.Errorf("Unexpected system instruction: %+v", out.SystemInstruction)
+	}
+	if len(out.Contents) != 3 || out.Contents[1].Role != "model" {
+		t.Fatalf("Unexpected contents: %+v", out.Contents)
+	}
+	if call := out.Contents[1].Parts[0].FunctionCall; call == nil || call.Name != "weather" {
+		t.Errorf("Unexpected function call: %+v", call)
+	}
+	if resp := out.Contents[2].Parts[0].FunctionResponse; resp == nil || resp.Name != "weather" || string(resp.Response) != `{"content":"sunny"}` {
+		t.Errorf("Unexpected function response: %+v", resp)
+	}
+	params := string(out.Tools[0].FunctionDeclarations[0].Parameters)
+	if strings.Contains(params, "additionalProperties") || !strings.Contains(params, `"strict"`) {
+		t.Errorf("Unexpected cleaned schema: %s", params)
+	}
+	if out.GenerationConfig.ResponseMimeType != "application/json" || *out.GenerationConfig.MaxOutputTokens != 256 {
+		t.Errorf("Unexpected generation config: %+v", out.GenerationConfig)
+	}
+}
+
+func TestGeminiConvertResponse(t *testing.T) {
+	body := []byte(`{
+		"candidates": [{"index": 0, "content": {"role": "model", "parts": [{"text": "Hello"}]}, "finishReason": "MAX_TOKENS"}],
+		"usageMetadata": {"promptTokenCount": 4, "candidatesTokenCount": 2, "totalTokenCount": 6}
+	}`)
+
+	data, err := (&GeminiAdapter{}).ConvertResponse(&Request{Model: "gemini-1.5-pro"}, body)
+	if err != nil {
+		t.Fatalf("ConvertResponse failed: %v", err)
+	}
+
+	var out openAIResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Invalid converted response: %v", err)
+	}
+	if *out.Choices[0].Message.Content != "Hello" || out.Choices[0].FinishReason != "length" {
+		t.Errorf("Unexpected choice: %+v", out.Choices[0])
+	}
+	if out.Usage.TotalTokens != 6 {
+		t.Errorf("Expected 6 total tokens, got %d", out.Usage.TotalTokens)
+	}
+}
+
+func TestGeminiStreamConverter(t *testing.T) {
+	converter := (&GeminiAdapter{}).NewStreamConverter(&Request{Model: "gemini-1.5-pro"})
+
+	first, err := converter.Convert([]byte(`{"candidates":[{"content":{"parts":[{"text":"Hel"}]}}]}`))
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !strings.Contains(string(first[0]), `"role":"assistant"`) || !strings.Contains(string(first[0]), `"content":"Hel"`) {
+		t.Errorf("Unexpected first chunk: %s", first[0])
+	}
+
+	last, err := converter.Convert([]byte(`{"candidates":[{"content":{"parts":[{"text":"lo"}]},"finishReason":"STOP"}]}`))
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if strings.Contains(string(last[0]), `"role"`) || !strings.Contains(string(last[0]), `"finish_reason":"stop"`) {
+		t.Errorf("Unexpected last chunk: %s", last[0])
+	}
+
+	done, err := converter.Finish()
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if len(done) != 1 || string(done[0]) != "[DONE]" {
+		t.Errorf("Expected [DONE], got %q", done)
+	}
+}
+
+func TestGeminiToolCallIDs(t *testing.T) {
+	parts := []string{
+		`{"text":"Checking"}`,
+		`{"functionCall":{"name":"weather","args":{"city":"Paris"}}}`,
+		`{"functionCall":{"name":"time","args":{"zone":"CET"}}}`,
+	}
+	var ids []string
+	data, err := (&GeminiAdapter{}).ConvertResponse(&Request{}, []byte(`{"candidates":[{"content":{"parts":[`+strings.Join(parts, ",")+`]},"finishReason":"STOP"}]}`))
+	if err != nil {
+		t.Fatalf("ConvertResponse failed: %v", err)
+	}
+	var out openAIResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Invalid converted response: %v", err)
+	}
+	for _, call := range out.Choices[0].Message.ToolCalls {
+		ids = append(ids, call.ID)
+	}
+
+	// 同一响应以流式返回时，每个事件一个 part，工具调用的 ID 与非流式相同
+	var streamIDs []string
+	converter := (&GeminiAdapter{}).NewStreamConverter(&Request{})
+	for _, part := range parts {
+		chunks, err := converter.Convert([]byte(`{"candidates":[{"content":{"parts":[` + part + `]}}]}`))
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		var chunk openAIChunk
+		if err := json.Unmarshal(chunks[0], &chunk); err != nil {
+			t.Fatalf("Invalid chunk: %v", err)
+		}
+		for _, call := range chunk.Choices[0].Delta.ToolCalls {
+			streamIDs = append(streamIDs, call.ID)
+		}
+	}
+
+	if len(ids) != 2 || strings.Join(ids, ",") != strings.Join(streamIDs, ",") {
+		t.Errorf("Expected the same tool call IDs, got %v and %v", ids, streamIDs)
+	}
+}
@@ -0,0 +1,91 @@
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusKey 上下文中记录实际状态码的键，响应头已发送后仍可记录失败状态
+const statusKey = "relayapi_status"
+
+// Body OpenAI 兼容的错误内容
+type Body struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    string  `json:"code"`
+}
+
+// Response OpenAI 兼容的错误响应
+type Response struct {
+	Error Body `json:"error"`
+}
+
+// New 根据状态码、错误码和描述创建错误响应
+func New(status int, code, message string) Response {
+	return Response{Error: Body{
+		Message: message,
+		Type:    typeForStatus(status),
+		Code:    code,
+	}}
+}
+
+// JSON 以 OpenAI 错误格式写出响应
+func JSON(c *gin.Context, status int, code, message string) {
+	c.JSON(status, New(status, code, message))
+}
+
+// Abort 以 OpenAI 错误格式写出响应并终止后续处理
+func Abort(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, New(status, code, message))
+}
+
+// WriteEvent 在已开始的 SSE 响应中写出错误事件，并记录实际状态码
+func WriteEvent(c *gin.Context, status int, code, message string) error {
+	SetStatus(c, status)
+	data, err := json.Marshal(New(status, code, message))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// SetStatus 记录请求的实际状态码，用于响应头已发送的场景
+func SetStatus(c *gin.Context, status int) {
+	c.Set(statusKey, status)
+}
+
+// Status 返回请求的实际状态码，供日志和统计使用
+func Status(c *gin.Context) int {
+	if status, ok := c.Get(statusKey); ok {
+		return status.(int)
+	}
+	return c.Writer.Status()
+}
+
+// typeForStatus 根据状态码确定错误类型
+func typeForStatus(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return "authentication_error"
+	case status == http.StatusForbidden:
+		return "permission_error"
+	case status == http.StatusNotFound:
+		return "not_found_error"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status == http.StatusGatewayTimeout:
+		return "timeout_error"
+	case status >= http.StatusInternalServerError:
+		return "server_error"
+	default:
+		return "invalid_request_error"
+	}
+}
@@ -0,0 +1,54 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	Abort(c, http.StatusTooManyRequests, "rate_limit_exceeded", "Too many requests")
+
+	if !c.IsAborted() || w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected aborted 429 response, got %d", w.Code)
+	}
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid error response: %v", err)
+	}
+	if resp.Error.Type != "rate_limit_error" || resp.Error.Code != "rate_limit_exceeded" || resp.Error.Message != "Too many requests" {
+		t.Errorf("Unexpected error body: %+v", resp.Error)
+	}
+	if !strings.Contains(w.Body.String(), `"param":null`) {
+		t.Errorf("Expected null param, got %s", w.Body.String())
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	// 模拟已开始的 SSE 响应
+	c.Header("Content-Type", "text/event-stream")
+	c.Writer.WriteString("data: {\"id\":1}\n\n")
+
+	if err := WriteEvent(c, http.StatusBadGateway, "upstream_error", "stream broken"); err != nil {
+		t.Fatalf("WriteEvent failed: %v", err)
+	}
+	if c.Writer.Status() != http.StatusOK || Status(c) != http.StatusBadGateway {
+		t.Errorf("Expected recorded status 502, got writer %d recorded %d", c.Writer.Status(), Status(c))
+	}
+	want := `data: {"error":{"message":"stream broken","type":"server_error","param":null,"code":"upstream_error"}}` + "\n\n"
+	if !strings.HasSuffix(w.Body.String(), want) {
+		t.Errorf("Unexpected stream body: %q", w.Body.String())
+	}
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"relayapi/server/internal/config"
+)
+
+// StatusKey 上下文中记录缓存命中情况的键，值为 StatusHit 或 StatusMiss
+const StatusKey = "cache_status"
+
+// 缓存命中情况
+const (
+	StatusHit  = "HIT"
+	StatusMiss = "MISS"
+)
+
+// DefaultMaxEntrySize 默认的单条缓存响应最大字节数 (1MB)
+const DefaultMaxEntrySize = 1 << 20
+
+// Entry 缓存的上游响应
+type Entry struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+// Expired 判断缓存条目是否已过期
+func (e *Entry) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// Response 将缓存条目还原为上游响应，流式响应也会按原样重放
+func (e *Entry) Response() *http.Response {
+	return &http.Response{
+		StatusCode:    e.StatusCode,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+	}
+}
+
+// Store 缓存存储后端
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry *Entry) error
+	Close() error
+}
+
+// Cache 上游响应缓存
+type Cache struct {
+	store        Store
+	maxEntrySize int64
+	responseDeny []string // 配置的不转发给客户端的响应头，缓存前删除
+}
+
+// New 根据服务器配置创建缓存，未启用时返回 nil
+func New(cfg *config.ServerConfig) (*Cache, error) {
+	if !cfg.Cache.Enabled {
+		return nil, nil
+	}
+
+	var store Store
+	switch cfg.Cache.Backend {
+	case "", "memory":
+		store = NewMemoryStore(cfg.Cache.MaxEntries)
+	case "sqlite":
+		sqliteStore, err := NewSQLiteStore(cfg.Cache.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		store = sqliteStore
+	default:
+		return nil, fmt.Errorf("unsupported cache backend: %s", cfg.Cache.Backend)
+	}
+
+	maxEntrySize := cfg.Cache.MaxEntrySize
+	if maxEntrySize <= 0 {
+		maxEntrySize = DefaultMaxEntrySize
+	}
+	return &Cache{store: store, maxEntrySize: maxEntrySize, responseDeny: cfg.Proxy.Headers.ResponseDeny}, nil
+}
+
+// Key 根据提供商、方法、路径和规范化后的请求体生成缓存键，不包含 API Key
+func Key(provider, method, path string, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", provider, method, path)
+	hash.Write(normalizeBody(body))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// normalizeBody 重新序列化 JSON 请求体，消除字段顺序和空白的差异
+func normalizeBody(body []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// Deterministic 判断请求是否有确定的结果：带 temperature 的请求必须为 0，
+// 没有 temperature 的对话和补全请求使用默认采样，不可缓存
+func Deterministic(body []byte) bool {
+	var req struct {
+		Temperature *float64        `json:"temperature"`
+		Messages    json.RawMessage `json:"messages"`
+		Prompt      json.RawMessage `json:"prompt"`
+	}
+	if len(body) > 0 && json.Unmarshal(body, &req) != nil {
+		return false
+	}
+	if req.Temperature != nil {
+		return *req.Temperature == 0
+	}
+	return req.Messages == nil && req.Prompt == nil
+}
+
+// Bypass 根据请求的 Cache-Control 头判断是否跳过读取和写入缓存
+func Bypass(header http.Header) (skipLookup, skipStore bool) {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "no-cache":
+				skipLookup = true
+			case "no-store":
+				skipLookup, skipStore = true, true
+			}
+		}
+	}
+	return skipLookup, skipStore
+}
+
+// Lookup 读取未过期的缓存响应
+func (c *Cache) Lookup(key string) (*http.Response, bool) {
+	entry, ok := c.store.Get(key)
+	if !ok || entry.Expired(time.Now()) {
+		return nil, false
+	}
+	return entry.Response(), true
+}
+
+// Record 包装上游响应体，成功读取完整响应后写入缓存，只缓存 2xx 响应
+func (c *Cache) Record(key string, ttl time.Duration, resp *http.Response) {
+	recordResponse(resp, c.maxEntrySize, ttl, c.responseDeny, func(entry *Entry) {
+		if err := c.store.Set(key, entry); err != nil {
+			slog.Warn("Failed to store cached response", "error", err)
+		}
+	})
+}
+
+// recordResponse 包装 2xx 响应体，完整读取后把响应交给 save 保存。
+// 保存的响应头只保留可以重放给其他调用方的头
+func recordResponse(resp *http.Response, maxSize int64, ttl time.Duration, responseDeny []string, save func(*Entry)) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || resp.ContentLength > maxSize {
+		return
+	}
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		maxSize:    maxSize,
+		save:       save,
+		entry: &Entry{
+			StatusCode: resp.StatusCode,
+			Header:     ShareableHeader(resp.Header, responseDeny),
+			ExpiresAt:  time.Now().Add(ttl),
+		},
+	}
+}
+
+// Close 关闭缓存后端
+func (c *Cache) Close() error {
+	return c.store.Close()
+}
+
+// recordingBody 在转发响应体的同时保存副本，读到 EOF 时写入缓存
+type recordingBody struct {
+	io.ReadCloser
+	maxSize  int64
+	save     func(*Entry)
+	entry    *Entry
+	buf      bytes.Buffer
+	overflow bool
+	done     bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.overflow {
+		if int64(b.buf.Len()+n) > b.maxSize {
+			b.overflow = true
+			b.buf.Reset()
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	// 只有完整读取的响应才写入缓存，客户端断开或上游出错的响应被丢弃
+	if err == io.EOF && !b.overflow && !b.done {
+		b.done = true
+		b.entry.Body = append([]byte(nil), b.buf.Bytes()...)
+		b.save(b.entry)
+	}
+	return n, err
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyNormalizesBody(t *testing.T) {
+	a := Key("openai", "POST", "https://api.openai.com/v1/embeddings", []byte(`{"model":"m","input":"hi"}`))
+	b := Key("openai", "POST", "https://api.openai.com/v1/embeddings", []byte(`{ "input": "hi", "model": "m" }`))
+	c := Key("anthropic", "POST", "https://api.openai.com/v1/embeddings", []byte(`{"model":"m","input":"hi"}`))
+	if a != b {
+		t.Error("Expected equal keys for equivalent JSON bodies")
+	}
+	if a == c {
+		t.Error("Expected different keys for different providers")
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	cases := map[string]bool{
+		`{"model":"m","input":"hi"}`:                    true,
+		`{"model":"m","messages":[],"temperature":0}`:   true,
+		`{"model":"m","messages":[],"temperature":0.7}`: false,
+		`{"model":"m","messages":[]}`:                   false,
+		`not json`:                                      false,
+	}
+	for body, want := range cases {
+		if got := Deterministic([]byte(body)); got != want {
+			t.Errorf("Deterministic(%s) = %v, want %v", body, got, want)
+		}
+	}
+}
+
+func TestBypass(t *testing.T) {
+	skipLookup, skipStore := Bypass(http.Header{"Cache-Control": {"max-age=0, no-cache"}})
+	if !skipLookup || skipStore {
+		t.Errorf("no-cache should only skip lookup, got %v %v", skipLookup, skipStore)
+	}
+	skipLookup, skipStore = Bypass(http.Header{"Cache-Control": {"no-store"}})
+	if !skipLookup || !skipStore {
+		t.Errorf("no-store should skip lookup and store, got %v %v", skipLookup, skipStore)
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	store := NewMemoryStore(2)
+	store.Set("a", &Entry{})
+	store.Set("b", &Entry{})
+	store.Get("a")
+	store.Set("c", &Entry{})
+
+	if _, ok := store.Get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := store.Get("a"); !ok {
+		t.Error("Expected recently used entry to be kept")
+	}
+	if store.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", store.Len())
+	}
+}
+
+func TestRecordAndReplayStream(t *testing.T) {
+	c := &Cache{store: NewMemoryStore(10), maxEntrySize: DefaultMaxEntrySize}
+	stream := "data: {\"id\":1}\n\ndata: [DONE]\n\n"
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"text/event-stream"}},
+		Body:          io.NopCloser(strings.NewReader(stream)),
+		ContentLength: -1,
+	}
+
+	c.Record("key", time.Minute, resp)
+	if _, ok := c.Lookup("key"); ok {
+		t.Fatal("Response should not be cached before it is fully read")
+	}
+	io.ReadAll(resp.Body)
+
+	cached, ok := c.Lookup("key")
+	if !ok {
+		t.Fatal("Expected cached response")
+	}
+	body, _ := io.ReadAll(cached.Body)
+	if string(body) != stream || cached.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Unexpected replayed response: %q %v", body, cached.Header)
+	}
+}
+
+func TestRecordSkipsErrorsAndExpiredEntries(t *testing.T) {
+	c := &Cache{store: NewMemoryStore(10), maxEntrySize: DefaultMaxEntrySize}
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Body: io.NopCloser(strings.NewReader("{}"))}
+	c.Record("error", time.Minute, resp)
+	io.ReadAll(resp.Body)
+	if _, ok := c.Lookup("error"); ok {
+		t.Error("Error responses should not be cached")
+	}
+
+	c.store.Set("expired", &Entry{StatusCode: http.StatusOK, ExpiresAt: time.Now().Add(-time.Second)})
+	if _, ok := c.Lookup("expired"); ok {
+		t.Error("Expired entries should not be returned")
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	entry := &Entry{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"data":[]}`),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	if err := store.Set("key", entry); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	store.Close()
+
+	// 重新打开后缓存仍然有效
+	store, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+	got, ok := store.Get("key")
+	if !ok || string(got.Body) != `{"data":[]}` || got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected cached entry: %+v", got)
+	}
+}
+
+func TestShareableHeader(t *testing.T) {
+	header := http.Header{
+		"Content-Type":                   {"application/json"},
+		"Openai-Model":                   {"gpt-4o"},
+		"Set-Cookie":                     {"__cf_bm=abc"},
+		"Date":                           {"Mon, 01 Jan 2024 00:00:00 GMT"},
+		"X-Request-Id":                   {"req_123"},
+		"X-Ratelimit-Remaining-Requests": {"99"},
+		"Anthropic-Ratelimit-Tokens":     {"1000"},
+		"X-Internal":                     {"1"},
+	}
+	shared := ShareableHeader(header, []string{"x-internal"})
+	want := http.Header{"Content-Type": {"application/json"}, "Openai-Model": {"gpt-4o"}}
+	if !reflect.DeepEqual(shared, want) {
+		t.Errorf("Expected %v, got %v", want, shared)
+	}
+	if header.Get("Set-Cookie") == "" {
+		t.Error("Expected the original header to be unchanged")
+	}
+}
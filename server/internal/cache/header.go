@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"net/http"
+	"strings"
+)
+
+// perResponseHeaders 只属于一次上游响应的头，缓存或共享给其他调用方之前删除
+var perResponseHeaders = []string{
+	"Set-Cookie",
+	"Set-Cookie2",
+	"Date",
+	"Age",
+	"Retry-After",
+	"Server-Timing",
+	"Cf-Ray",
+	"Openai-Organization",
+	"Openai-Processing-Ms",
+	"Anthropic-Organization-Id",
+	"X-Envoy-Upstream-Service-Time",
+}
+
+// perResponseHeaderMarkers 名称包含这些片段的头（请求 ID、链路追踪、限流额度）同样只属于一次响应
+var perResponseHeaderMarkers = []string{"request-id", "requestid", "trace-id", "traceparent", "ratelimit", "rate-limit"}
+
+// ShareableHeader 返回可以重放给其他调用方的响应头副本：删除 Set-Cookie、Date、请求 ID、
+// 限流额度等只属于一次上游响应的头，以及 responseDeny 中配置的响应头
+func ShareableHeader(header http.Header, responseDeny []string) http.Header {
+	shared := header.Clone()
+	if shared == nil {
+		return make(http.Header)
+	}
+	for _, name := range perResponseHeaders {
+		shared.Del(name)
+	}
+	for _, name := range responseDeny {
+		shared.Del(strings.TrimSpace(name))
+	}
+	for key := range shared {
+		lower := strings.ToLower(key)
+		for _, marker := range perResponseHeaderMarkers {
+			if strings.Contains(lower, marker) {
+				delete(shared, key)
+				break
+			}
+		}
+	}
+	return shared
+}
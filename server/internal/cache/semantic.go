@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"relayapi/server/internal/config"
+)
+
+// StatusSemanticHit 语义缓存命中
+const StatusSemanticHit = "SEMANTIC_HIT"
+
+// 语义缓存的默认设置
+const (
+	DefaultSemanticThreshold = 0.95
+	DefaultSemanticTTL       = 3600
+	DefaultEmbeddingModel    = "text-embedding-3-small"
+)
+
+// VectorIndex 进程内的向量索引，按作用域线性扫描余弦相似度
+type VectorIndex struct {
+	mu         sync.RWMutex
+	maxEntries int
+	entries    []*vectorEntry // 按写入顺序排列，超过容量时淘汰最早的条目
+}
+
+// vectorEntry 向量索引中的条目，向量已归一化
+type vectorEntry struct {
+	scope  string
+	vector []float32
+	entry  *Entry
+}
+
+// NewVectorIndex 创建向量索引，maxEntries 不大于 0 时使用默认值
+func NewVectorIndex(maxEntries int) *VectorIndex {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &VectorIndex{maxEntries: maxEntries}
+}
+
+// Add 添加向量及对应的缓存响应
+func (i *VectorIndex) Add(scope string, vector []float32, entry *Entry) {
+	normalized := normalize(vector)
+	if normalized == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// 顺便清理过期条目
+	now := time.Now()
+	kept := i.entries[:0]
+	for _, e := range i.entries {
+		if !e.entry.Expired(now) {
+			kept = append(kept, e)
+		}
+	}
+	i.entries = append(kept, &vectorEntry{scope: scope, vector: normalized, entry: entry})
+	if over := len(i.entries) - i.maxEntries; over > 0 {
+		i.entries = append(i.entries[:0:0], i.entries[over:]...)
+	}
+}
+
+// Search 查找同一作用域中相似度最高且不低于 threshold 的未过期条目
+func (i *VectorIndex) Search(scope string, vector []float32, threshold float64) (*Entry, float64, bool) {
+	query := normalize(vector)
+	if query == nil {
+		return nil, 0, false
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	now := time.Now()
+	var best *Entry
+	bestScore := threshold
+	for _, e := range i.entries {
+		if e.scope != scope || len(e.vector) != len(query) || e.entry.Expired(now) {
+			continue
+		}
+		if score := dot(e.vector, query); score >= bestScore {
+			best, bestScore = e.entry, score
+		}
+	}
+	if best == nil {
+		return nil, 0, false
+	}
+	return best, bestScore, true
+}
+
+// Len 返回索引中的条目数
+func (i *VectorIndex) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entries)
+}
+
+// normalize 返回单位向量，零向量返回 nil
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return nil
+	}
+	norm := math.Sqrt(sum)
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// dot 计算两个向量的点积，归一化向量的点积即余弦相似度
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// SemanticCache 按提示词语义相似度匹配的响应缓存
+type SemanticCache struct {
+	index        *VectorIndex
+	threshold    float64
+	ttl          time.Duration
+	maxEntrySize int64
+	responseDeny []string
+}
+
+// NewSemantic 根据服务器配置创建语义缓存，未启用时返回 nil
+func NewSemantic(cfg *config.ServerConfig) *SemanticCache {
+	semantic := cfg.Cache.Semantic
+	if !semantic.Enabled {
+		return nil
+	}
+
+	threshold := semantic.Threshold
+	if threshold <= 0 {
+		threshold = DefaultSemanticThreshold
+	}
+	ttl := semantic.TTL
+	if ttl <= 0 {
+		ttl = DefaultSemanticTTL
+	}
+	maxEntrySize := cfg.Cache.MaxEntrySize
+	if maxEntrySize <= 0 {
+		maxEntrySize = DefaultMaxEntrySize
+	}
+	return &SemanticCache{
+		index:        NewVectorIndex(semantic.MaxEntries),
+		threshold:    threshold,
+		ttl:          time.Duration(ttl) * time.Second,
+		maxEntrySize: maxEntrySize,
+		responseDeny: cfg.Proxy.Headers.ResponseDeny,
+	}
+}
+
+// Lookup 返回作用域内与 vector 足够相似的缓存响应及其相似度
+func (s *SemanticCache) Lookup(scope string, vector []float32) (*http.Response, float64, bool) {
+	entry, score, ok := s.index.Search(scope, vector, s.threshold)
+	if !ok {
+		return nil, 0, false
+	}
+	return entry.Response(), score, true
+}
+
+// Record 包装上游响应体，成功读取完整响应后加入向量索引
+func (s *SemanticCache) Record(scope string, vector []float32, resp *http.Response) {
+	recordResponse(resp, s.maxEntrySize, s.ttl, s.responseDeny, func(entry *Entry) {
+		s.index.Add(scope, vector, entry)
+	})
+}
+
+// semanticMessageFields 语义缓存支持的消息字段，带有 tool_calls 等其他字段的消息不缓存
+var semanticMessageFields = map[string]bool{"role": true, "content": true, "name": true}
+
+// Prompt 提取对话请求中用于计算嵌入的提示词，以及区分缓存的作用域：messages 以外的全部请求字段
+// （模型、是否流式、tools、response_format、temperature 等），只有这些字段完全相同的请求才会共享响应。
+// 对话包含工具调用、工具结果或图片等非文本内容时无法只按文本判断相似，不使用语义缓存
+func Prompt(body []byte) (prompt, scope string, ok bool) {
+	var req map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		return "", "", false
+	}
+	messages, _ := req["messages"].([]interface{})
+	if len(messages) == 0 {
+		return "", "", false
+	}
+
+	var text strings.Builder
+	for _, item := range messages {
+		message, isObject := item.(map[string]interface{})
+		if !isObject {
+			return "", "", false
+		}
+		for field := range message {
+			if !semanticMessageFields[field] {
+				return "", "", false
+			}
+		}
+		role, _ := message["role"].(string)
+		if role != "system" && role != "developer" && role != "user" && role != "assistant" {
+			return "", "", false
+		}
+		content, isText := messageText(message["content"])
+		if !isText {
+			return "", "", false
+		}
+		if content == "" {
+			continue
+		}
+		text.WriteString(role)
+		text.WriteString(": ")
+		text.WriteString(content)
+		text.WriteString("\n")
+	}
+	if text.Len() == 0 {
+		return "", "", false
+	}
+
+	// 重新序列化的 JSON 按键排序，字段顺序和空白不同的相同请求得到相同的作用域
+	delete(req, "messages")
+	params, err := json.Marshal(req)
+	if err != nil {
+		return "", "", false
+	}
+	return text.String(), string(params), true
+}
+
+// messageText 提取消息内容中的文本，支持字符串和内容片段数组。
+// 内容不是文本（为空、包含图片或音频等片段）时 isText 为 false
+func messageText(content interface{}) (text string, isText bool) {
+	switch v := content.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		texts := make([]string, 0, len(v))
+		for _, item := range v {
+			part, _ := item.(map[string]interface{})
+			partText, _ := part["text"].(string)
+			if part["type"] != "text" {
+				return "", false
+			}
+			if partText != "" {
+				texts = append(texts, partText)
+			}
+		}
+		return strings.Join(texts, "\n"), true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVectorIndexSearch(t *testing.T) {
+	index := NewVectorIndex(10)
+	hello := &Entry{Body: []byte("hello"), ExpiresAt: time.Now().Add(time.Hour)}
+	other := &Entry{Body: []byte("other"), ExpiresAt: time.Now().Add(time.Hour)}
+	index.Add("gpt-4o", []float32{1, 0, 0}, hello)
+	index.Add("gpt-4o", []float32{0, 1, 0}, other)
+
+	entry, score, ok := index.Search("gpt-4o", []float32{0.9, 0.1, 0}, 0.95)
+	if !ok || entry != hello || score < 0.95 {
+		t.Errorf("Expected similar entry, got %v %.4f %v", entry, score, ok)
+	}
+	if _, _, ok := index.Search("gpt-4o", []float32{1, 1, 0}, 0.95); ok {
+		t.Error("Dissimilar vector should not match")
+	}
+	if _, _, ok := index.Search("gpt-4o-mini", []float32{1, 0, 0}, 0.95); ok {
+		t.Error("Entries from another scope should not match")
+	}
+}
+
+func TestVectorIndexEviction(t *testing.T) {
+	index := NewVectorIndex(2)
+	index.Add("s", []float32{1, 0}, &Entry{ExpiresAt: time.Now().Add(time.Hour)})
+	index.Add("s", []float32{0, 1}, &Entry{ExpiresAt: time.Now().Add(time.Hour)})
+	index.Add("s", []float32{1, 1}, &Entry{ExpiresAt: time.Now().Add(time.Hour)})
+
+	if index.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", index.Len())
+	}
+	if _, _, ok := index.Search("s", []float32{1, 0}, 0.99); ok {
+		t.Error("Oldest entry should have been evicted")
+	}
+}
+
+func TestSemanticCacheRecord(t *testing.T) {
+	s := &SemanticCache{index: NewVectorIndex(10), threshold: 0.9, ttl: time.Minute, maxEntrySize: DefaultMaxEntrySize}
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id":"chatcmpl-1"}`)),
+	}
+	s.Record("scope", []float32{0.6, 0.8}, resp)
+	io.ReadAll(resp.Body)
+
+	cached, score, ok := s.Lookup("scope", []float32{0.61, 0.79})
+	if !ok || score < 0.9 {
+		t.Fatalf("Expected semantic hit, got %.4f %v", score, ok)
+	}
+	body, _ := io.ReadAll(cached.Body)
+	if string(body) != `{"id":"chatcmpl-1"}` {
+		t.Errorf("Unexpected cached body: %s", body)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	prompt, scope, ok := Prompt([]byte(`{
+		"model": "gpt-4o",
+		"stream": true,
+		"temperature": 0.2,
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": [{"type": "text", "text": "What is Go?"}]}
+		]
+	}`))
+	if !ok {
+		t.Fatal("Expected prompt to be extracted")
+	}
+	if prompt != "system: Be brief.\nuser: What is Go?\n" {
+		t.Errorf("Unexpected prompt: %q", prompt)
+	}
+	if scope != `{"model":"gpt-4o","stream":true,"temperature":0.2}` {
+		t.Errorf("Unexpected scope: %q", scope)
+	}
+
+	// 除 messages 外的请求字段不同时作用域不同，字段顺序不影响作用域
+	messages := `"messages":[{"role":"user","content":"What is Go?"}]`
+	scopes := make(map[string]bool)
+	for _, body := range []string{
+		`{"model":"gpt-4o",` + messages + `}`,
+		`{"model":"gpt-4o","max_tokens":10,` + messages + `}`,
+		`{"model":"gpt-4o","response_format":{"type":"json_object"},` + messages + `}`,
+		`{"model":"gpt-4o","tools":[{"type":"function","function":{"name":"weather"}}],` + messages + `}`,
+		`{"model":"gpt-4o","tools":[{"type":"function","function":{"name":"search"}}],` + messages + `}`,
+	} {
+		_, scope, ok := Prompt([]byte(body))
+		if !ok {
+			t.Fatalf("Expected prompt for %s", body)
+		}
+		scopes[scope] = true
+	}
+	if len(scopes) != 5 {
+		t.Errorf("Expected 5 distinct scopes, got %d", len(scopes))
+	}
+	_, reordered, _ := Prompt([]byte(`{` + messages + `,"max_tokens":10, "model":"gpt-4o"}`))
+	if !scopes[reordered] {
+		t.Errorf("Expected field order not to change the scope, got %q", reordered)
+	}
+
+	for _, body := range []string{
+		`{"model":"m","input":"embedding"}`,
+		// 图片等非文本内容
+		`{"model":"m","messages":[{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"x"}}]}]}`,
+		// 助手的工具调用和工具结果
+		`{"model":"m","messages":[{"role":"user","content":"Weather?"},{"role":"assistant","content":null,"tool_calls":[{"id":"1"}]}]}`,
+		`{"model":"m","messages":[{"role":"user","content":"Weather?"},{"role":"tool","tool_call_id":"1","content":"Sunny"}]}`,
+	} {
+		if _, _, ok := Prompt([]byte(body)); ok {
+			t.Errorf("Expected no semantic prompt for %s", body)
+		}
+	}
+}
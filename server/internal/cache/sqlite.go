@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore 基于 SQLite 的磁盘缓存，重启后仍然有效
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore 打开或创建 SQLite 缓存数据库，并清理已过期的条目
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database: %v", err)
+	}
+
+	createTableSQL := `
+		CREATE TABLE IF NOT EXISTS response_cache (
+			cache_key TEXT PRIMARY KEY,
+			status_code INTEGER,
+			header TEXT,
+			body BLOB,
+			expires_at INTEGER
+		)`
+	if _, err := db.Exec(createTableSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create cache table: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM response_cache WHERE expires_at <= ?`, time.Now().Unix()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to purge expired cache entries: %v", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Get 读取未过期的缓存条目，过期条目会被删除
+func (s *SQLiteStore) Get(key string) (*Entry, bool) {
+	var (
+		statusCode int
+		header     string
+		body       []byte
+		expiresAt  int64
+	)
+	err := s.db.QueryRow(
+		`SELECT status_code, header, body, expires_at FROM response_cache WHERE cache_key = ?`, key,
+	).Scan(&statusCode, &header, &body, &expiresAt)
+	if err != nil {
+		return nil, false
+	}
+
+	entry := &Entry{StatusCode: statusCode, Body: body, ExpiresAt: time.Unix(expiresAt, 0)}
+	if entry.Expired(time.Now()) {
+		s.db.Exec(`DELETE FROM response_cache WHERE cache_key = ?`, key)
+		return nil, false
+	}
+	entry.Header = make(http.Header)
+	if err := json.Unmarshal([]byte(header), &entry.Header); err != nil {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Set 写入或覆盖缓存条目
+func (s *SQLiteStore) Set(key string, entry *Entry) error {
+	header, err := json.Marshal(entry.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT OR REPLACE INTO response_cache (cache_key, status_code, header, body, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		key, entry.StatusCode, string(header), entry.Body, entry.ExpiresAt.Unix(),
+	)
+	return err
+}
+
+// Close 关闭数据库连接
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,33 @@
+package certs
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// Identities 返回客户端证书的身份，格式与 tls.client_auth.profiles 的 match 相同：
+// cn:<主题 CN>、dns:<DNS SAN>、uri:<URI SAN>、email:<邮箱 SAN>
+func Identities(cert *x509.Certificate) []string {
+	var identities []string
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, "cn:"+cert.Subject.CommonName)
+	}
+	for _, name := range cert.DNSNames {
+		identities = append(identities, "dns:"+name)
+	}
+	for _, uri := range cert.URIs {
+		identities = append(identities, "uri:"+uri.String())
+	}
+	for _, email := range cert.EmailAddresses {
+		identities = append(identities, "email:"+email)
+	}
+	return identities
+}
+
+// VerifiedClientCert 返回请求连接上已通过 CA 校验的客户端证书，没有时返回 nil
+func VerifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
@@ -0,0 +1,109 @@
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay 合并证书轮换时产生的连续文件事件（证书和私钥通常先后写入）
+const reloadDelay = 500 * time.Millisecond
+
+// Reloader 从文件加载证书，文件更新时自动重新加载，新证书无效时继续使用当前证书
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloader 加载证书和私钥
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新读取证书和私钥，失败时保留当前证书
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate 返回当前证书，用于 tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch 监控证书和私钥所在的目录（兼容 Kubernetes Secret 以符号链接替换文件的方式），
+// 文件变化时重新加载，stop 关闭后返回
+func (r *Reloader) Watch(stop <-chan struct{}) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Failed to create certificate watcher", "error", err)
+		return
+	}
+	defer watcher.Close()
+
+	for _, dir := range uniqueDirs(r.certFile, r.keyFile) {
+		if err := watcher.Add(dir); err != nil {
+			slog.Warn("Failed to watch certificate directory", "path", dir, "error", err)
+			return
+		}
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			timer.Reset(reloadDelay)
+		case <-timer.C:
+			if err := r.Reload(); err != nil {
+				slog.Error("Failed to reload TLS certificate, keeping the current one", "path", r.certFile, "error", err)
+			} else {
+				slog.Info("Reloaded TLS certificate", "path", r.certFile)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("Certificate watcher error", "error", err)
+		}
+	}
+}
+
+// uniqueDirs 返回文件所在的目录，去掉重复
+func uniqueDirs(files ...string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
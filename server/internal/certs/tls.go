@@ -0,0 +1,151 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"relayapi/server/internal/config"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Setup 根据配置创建的 TLS 设置
+type Setup struct {
+	TLSConfig *tls.Config
+
+	// HTTPHandler 包装 HTTP 重定向监听的处理器，启用 ACME 时处理 http-01 验证请求
+	HTTPHandler func(fallback http.Handler) http.Handler
+
+	reloader *Reloader
+}
+
+// New 根据服务器配置创建 TLS 设置，证书文件由 Watch 监控
+func New(cfg *config.ServerConfig) (*Setup, error) {
+	tlsCfg := cfg.TLS
+	setup := &Setup{
+		HTTPHandler: func(fallback http.Handler) http.Handler { return fallback },
+	}
+
+	if tlsCfg.ACME.Enabled {
+		manager, err := newACMEManager(cfg)
+		if err != nil {
+			return nil, err
+		}
+		// 包含 acme-tls/1 协议，支持 tls-alpn-01 验证
+		setup.TLSConfig = manager.TLSConfig()
+		setup.HTTPHandler = manager.HTTPHandler
+	} else {
+		reloader, err := NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		setup.reloader = reloader
+		setup.TLSConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+	}
+
+	setup.TLSConfig.MinVersion = tls.VersionTLS12
+	if tlsCfg.MinVersion == "1.3" {
+		setup.TLSConfig.MinVersion = tls.VersionTLS13
+	}
+
+	// 校验客户端证书，证书身份由 TokenAuth 映射为服务端令牌
+	if clientAuth := tlsCfg.ClientAuth; clientAuth.Enabled {
+		pool, err := loadCertPool(clientAuth.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client CA: %v", err)
+		}
+		setup.TLSConfig.ClientCAs = pool
+		setup.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if clientAuth.Required {
+			setup.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return setup, nil
+}
+
+// Watch 监控证书文件的变化，ACME 证书由 autocert 自动续期
+func (s *Setup) Watch(stop <-chan struct{}) {
+	if s.reloader != nil {
+		s.reloader.Watch(stop)
+	}
+}
+
+// Configure 为服务器启用 TLS，disableHTTP2 为 true 时只使用 HTTP/1.1
+func (s *Setup) Configure(server *http.Server, disableHTTP2 bool) {
+	server.TLSConfig = s.TLSConfig
+	if disableHTTP2 {
+		// 非 nil 的空 map 会阻止 net/http 自动启用 HTTP/2，同时不再通过 ALPN 声明 h2
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		tlsConfig := s.TLSConfig.Clone()
+		tlsConfig.NextProtos = nil
+		for _, proto := range s.TLSConfig.NextProtos {
+			if proto != "h2" {
+				tlsConfig.NextProtos = append(tlsConfig.NextProtos, proto)
+			}
+		}
+		server.TLSConfig = tlsConfig
+	}
+}
+
+// newACMEManager 创建自动申请和续期证书的 autocert 管理器
+func newACMEManager(cfg *config.ServerConfig) (*autocert.Manager, error) {
+	acmeCfg := cfg.TLS.ACME
+	cacheDir := acmeCfg.CacheDir
+	if cacheDir == "" {
+		cacheDir = config.DefaultACMECacheDir
+	}
+
+	client := &acme.Client{DirectoryURL: acmeCfg.DirectoryURL}
+	if acmeCfg.CAFile != "" {
+		pool, err := loadCertPool(acmeCfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ACME CA: %v", err)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(acmeCfg.Domains...),
+		Email:      acmeCfg.Email,
+		Client:     client,
+	}, nil
+}
+
+// loadCertPool 读取 PEM 格式的 CA 证书
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+// RedirectHandler 将 HTTP 请求永久重定向到 HTTPS，httpsPort 为 443 时省略端口。
+// 使用 308 保留请求方法和请求体
+func RedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
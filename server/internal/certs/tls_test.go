@@ -0,0 +1,287 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"relayapi/server/internal/config"
+)
+
+// writeCert 生成自签名证书并写入文件，返回证书的序列号
+func writeCert(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serialOf(t *testing.T, r *Reloader) int64 {
+	t.Helper()
+	cert, _ := r.GetCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+func TestReloaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, 1)
+
+	reloader, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go reloader.Watch(stop)
+	time.Sleep(100 * time.Millisecond)
+
+	// 证书轮换后自动加载新证书
+	writeCert(t, certFile, keyFile, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	for serialOf(t, reloader) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for certificate reload")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// 无效的证书不会替换当前证书
+	if err := os.WriteFile(certFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloader.Reload(); err == nil {
+		t.Error("Expected invalid certificate to be rejected")
+	}
+	if serialOf(t, reloader) != 2 {
+		t.Error("Expected the current certificate to be kept")
+	}
+}
+
+func TestHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.ServerConfig{}
+	cfg.TLS.Enabled = true
+	cfg.TLS.CertFile, cfg.TLS.KeyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, cfg.TLS.CertFile, cfg.TLS.KeyFile, 1)
+
+	for _, tt := range []struct {
+		disableHTTP2 bool
+		proto        string
+	}{
+		{false, "HTTP/2.0"},
+		{true, "HTTP/1.1"},
+	} {
+		setup, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		})}
+		setup.Configure(server, tt.disableHTTP2)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go server.ServeTLS(listener, "", "")
+
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		}}
+		resp, err := client.Get("https://" + listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Proto != tt.proto {
+			t.Errorf("disable_http2=%v: expected %s, got %s", tt.disableHTTP2, tt.proto, resp.Proto)
+		}
+		server.Close()
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.ServerConfig{}
+	cfg.TLS.Enabled = true
+	cfg.TLS.CertFile, cfg.TLS.KeyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, cfg.TLS.CertFile, cfg.TLS.KeyFile, 1)
+	// 自签名的客户端证书同时作为客户端 CA
+	clientCert, clientKey := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeCert(t, clientCert, clientKey, 2)
+	cfg.TLS.ClientAuth.Enabled = true
+	cfg.TLS.ClientAuth.CAFile = clientCert
+
+	keyPair, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, required := range []bool{false, true} {
+		cfg.TLS.ClientAuth.Required = required
+		setup, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cert := VerifiedClientCert(r); cert != nil {
+				w.Write([]byte(Identities(cert)[0]))
+			}
+		})}
+		setup.Configure(server, false)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go server.ServeTLS(listener, "", "")
+
+		get := func(certificates []tls.Certificate) (string, error) {
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				Certificates:       certificates,
+			}}}
+			resp, err := client.Get("https://" + listener.Addr().String())
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			return string(body), err
+		}
+
+		if identity, err := get([]tls.Certificate{keyPair}); err != nil || identity != "cn:localhost" {
+			t.Errorf("required=%v: expected verified client certificate, got %q (%v)", required, identity, err)
+		}
+		identity, err := get(nil)
+		if required && err == nil {
+			t.Error("Expected connection without client certificate to be rejected")
+		}
+		if !required && (err != nil || identity != "") {
+			t.Errorf("Expected connection without client certificate to be accepted, got %q (%v)", identity, err)
+		}
+		server.Close()
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port   int
+		target string
+	}{
+		{443, "https://example.com/relayapi/models?token=x"},
+		{8443, "https://example.com:8443/relayapi/models?token=x"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com:80/relayapi/models?token=x", nil)
+		w := httptest.NewRecorder()
+		RedirectHandler(tt.port).ServeHTTP(w, req)
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != tt.target {
+			t.Errorf("Expected 308 to %s, got %d %s", tt.target, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+// TestACMEDirectory 使用本地的 ACME 目录替身（与 Pebble 一样使用自签名 HTTPS）验证目录地址和 CA 配置
+func TestACMEDirectory(t *testing.T) {
+	directory := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "https://" + r.Host
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   base + "/nonce",
+			"newAccount": base + "/account",
+			"newOrder":   base + "/order",
+			"revokeCert": base + "/revoke",
+			"keyChange":  base + "/key-change",
+		})
+	}))
+	defer directory.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: directory.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.ServerConfig{}
+	cfg.TLS.Enabled = true
+	cfg.TLS.ACME.Enabled = true
+	cfg.TLS.ACME.Domains = []string{"relay.example.com"}
+	cfg.TLS.ACME.CacheDir = t.TempDir()
+	cfg.TLS.ACME.DirectoryURL = directory.URL + "/dir"
+	cfg.TLS.ACME.CAFile = caFile
+
+	manager, err := newACMEManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := manager.Client.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to discover ACME directory: %v", err)
+	}
+	if dir.OrderURL != directory.URL+"/order" {
+		t.Errorf("Unexpected order URL: %s", dir.OrderURL)
+	}
+
+	// 只为配置的域名申请证书
+	if err := manager.HostPolicy(context.Background(), "other.example.com"); err == nil {
+		t.Error("Expected unknown domain to be rejected")
+	}
+
+	// 非验证请求交给重定向处理器
+	setup, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	setup.HTTPHandler(RedirectHandler(443)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://relay.example.com/health", nil))
+	if w.Code != http.StatusPermanentRedirect {
+		t.Errorf("Expected redirect, got %d", w.Code)
+	}
+	if setup.TLSConfig.GetCertificate == nil {
+		t.Error("Expected ACME certificates to be served through GetCertificate")
+	}
+}
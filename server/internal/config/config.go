@@ -2,68 +2,316 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
 // ValidateConfig 验证配置是否有效
 func ValidateConfig(cfg *Config) error {
+	if err := ValidateServerConfig(&cfg.Server); err != nil {
+		return err
+	}
+
+	// 验证客户端配置
+	if cfg.Clients.Len() == 0 {
+		return fmt.Errorf("no client configurations found")
+	}
+
+	for hash, clientCfg := range cfg.ClientConfigs() {
+		if err := ValidateClientConfig(&clientCfg); err != nil {
+			return fmt.Errorf("%v for config %s", err, hash)
+		}
+	}
+
+	return nil
+}
+
+// ValidateServerConfig 验证服务器配置是否有效，热重载 config.json 时也使用它
+func ValidateServerConfig(server *ServerConfig) error {
+	return CheckServerConfig(server).Err()
+}
+
+// ValidateClientConfig 验证单个客户端配置是否有效
+func ValidateClientConfig(clientCfg *ClientConfig) error {
+	return CheckClientConfig(clientCfg).Err()
+}
+
+// CheckServerConfig 检查服务器配置的取值，返回全部问题
+func CheckServerConfig(server *ServerConfig) Problems {
+	var problems Problems
+
 	// 验证服务器配置
-	if cfg.Server.Server.Port <= 0 {
-		return fmt.Errorf("invalid server port")
+	if server.Server.Port <= 0 {
+		problems.add("server.port", "invalid server port")
+	}
+	if server.Server.ReadTimeout <= 0 {
+		problems.add("server.read_timeout", "invalid read timeout")
 	}
-	if cfg.Server.Server.ReadTimeout <= 0 {
-		return fmt.Errorf("invalid read timeout")
+	if server.Server.WriteTimeout <= 0 {
+		problems.add("server.write_timeout", "invalid write timeout")
 	}
-	if cfg.Server.Server.WriteTimeout <= 0 {
-		return fmt.Errorf("invalid write timeout")
+	if server.Server.ShutdownTimeout < 0 {
+		problems.add("server.shutdown_timeout", "shutdown timeout must not be negative")
+	}
+
+	// 验证代理超时配置
+	timeouts := server.Proxy.Timeouts
+	for _, timeout := range []struct {
+		name  string
+		value int
+	}{
+		{"first_byte", timeouts.FirstByte},
+		{"idle", timeouts.Idle},
+		{"stream", timeouts.Stream},
+		{"keepalive", timeouts.Keepalive},
+	} {
+		if timeout.value < 0 {
+			problems.add("proxy.timeouts."+timeout.name, "proxy timeouts must not be negative")
+		}
+	}
+
+	// 验证缓存配置
+	if server.Cache.Enabled {
+		switch server.Cache.Backend {
+		case "", "memory":
+		case "sqlite":
+			if server.Cache.SQLitePath == "" {
+				problems.add("cache.sqlite_path", "sqlite cache enabled but sqlite path is empty")
+			}
+		default:
+			problems.add("cache.backend", "unsupported cache backend: %s", server.Cache.Backend)
+		}
+	}
+
+	if semantic := server.Cache.Semantic; semantic.Enabled {
+		if semantic.Threshold < 0 || semantic.Threshold > 1 {
+			problems.add("cache.semantic.threshold", "semantic cache threshold must be between 0 and 1")
+		}
+	}
+
+	// 验证管理接口配置
+	if err := server.CheckAdminToken(server.ListenerConfigs()); err != nil {
+		problems.add("admin.token", "%v", err)
+	}
+
+	// 验证监听配置
+	checkListeners(server, &problems)
+
+	// 验证 TLS 配置
+	if tlsCfg := server.TLS; tlsCfg.Enabled {
+		if tlsCfg.ACME.Enabled {
+			if len(tlsCfg.ACME.Domains) == 0 {
+				problems.add("tls.acme.domains", "ACME enabled without domains")
+			}
+		} else {
+			if tlsCfg.CertFile == "" {
+				problems.add("tls.cert_file", "TLS enabled without certificate file or ACME")
+			}
+			if tlsCfg.KeyFile == "" {
+				problems.add("tls.key_file", "TLS enabled without key file or ACME")
+			}
+		}
+		switch tlsCfg.MinVersion {
+		case "", "1.2", "1.3":
+		default:
+			problems.add("tls.min_version", "unsupported TLS version: %s", tlsCfg.MinVersion)
+		}
+		if tlsCfg.RedirectHTTP != "" {
+			if _, err := server.RedirectPort(); err != nil {
+				problems.add("tls.redirect_http", "%v", err)
+			}
+		}
+	} else if server.TLS.RedirectHTTP != "" {
+		problems.add("tls.redirect_http", "HTTP redirect requires TLS to be enabled")
+	}
+
+	// 验证客户端证书认证配置
+	if clientAuth := server.TLS.ClientAuth; clientAuth.Enabled {
+		if !server.TLS.Enabled {
+			problems.add("tls.client_auth.enabled", "client certificate authentication requires TLS to be enabled")
+		}
+		if clientAuth.CAFile == "" {
+			problems.add("tls.client_auth.ca_file", "client certificate authentication enabled without CA file")
+		}
+		checkClientCertProfiles(server, &problems)
 	}
 
 	// 验证日志配置
-	if cfg.Server.Log.Database.Enabled {
-		if cfg.Server.Log.Database.ConnectionString == "" {
-			return fmt.Errorf("database logging enabled but connection string is empty")
+	if server.Log.Database.Enabled {
+		if server.Log.Database.ConnectionString == "" {
+			problems.add("log.database.connection_string", "database logging enabled but connection string is empty")
 		}
 	}
-	if cfg.Server.Log.Web.Enabled {
-		if cfg.Server.Log.Web.CallbackURL == "" {
-			return fmt.Errorf("web logging enabled but callback URL is empty")
+	if server.Log.Web.Enabled {
+		if server.Log.Web.CallbackURL == "" {
+			problems.add("log.web.callback_url", "web logging enabled but callback URL is empty")
 		}
 	}
-	if cfg.Server.Log.Parquet.Enabled {
-		if cfg.Server.Log.Parquet.FilePath == "" {
-			return fmt.Errorf("parquet logging enabled but file path is empty")
+	if server.Log.Parquet.Enabled {
+		if server.Log.Parquet.FilePath == "" {
+			problems.add("log.parquet.file_path", "parquet logging enabled but file path is empty")
 		}
 	}
 
 	// 验证速率限制配置
-	if cfg.Server.RateLimit.RequestsPerSecond <= 0 {
-		return fmt.Errorf("invalid requests per second")
+	if server.RateLimit.RequestsPerSecond <= 0 {
+		problems.add("rate_limit.requests_per_second", "invalid requests per second")
 	}
-	if cfg.Server.RateLimit.Burst <= 0 {
-		return fmt.Errorf("invalid burst size")
+	if server.RateLimit.Burst <= 0 {
+		problems.add("rate_limit.burst", "invalid burst size")
 	}
 
-	// 验证客户端配置
-	if len(cfg.Clients) == 0 {
-		return fmt.Errorf("no client configurations found")
+	return problems
+}
+
+// checkListeners 检查 listeners 中的监听配置
+func checkListeners(server *ServerConfig, problems *Problems) {
+	if len(server.Listeners) == 0 {
+		return
+	}
+	if server.Admin.Listen != "" {
+		problems.add("admin.listen", "admin.listen is ignored when listeners are configured, add a listener with the admin route instead")
 	}
 
-	for hash, clientCfg := range cfg.Clients {
-		// 验证服务器配置
-		if clientCfg.Server.Port <= 0 {
-			return fmt.Errorf("invalid client server port for config %s", hash)
+	usesTLS := false
+	for i, l := range server.Listeners {
+		path := fmt.Sprintf("listeners[%d]", i)
+		switch l.Network {
+		case "", NetworkTCP, NetworkUnix:
+			if l.Address == "" {
+				problems.add(path+".address", "listener has no address")
+			}
+		case NetworkSystemd:
+		default:
+			problems.add(path+".network", "unsupported listener network: %s", l.Network)
 		}
 
-		// 验证加密配置
-		if clientCfg.Crypto.Method != "aes" {
-			return fmt.Errorf("unsupported encryption method: %s for config %s", clientCfg.Crypto.Method, hash)
+		for j, route := range l.Routes {
+			routePath := fmt.Sprintf("%s.routes[%d]", path, j)
+			if !slices.Contains(allRoutes, route) {
+				problems.add(routePath, "unknown route group %q, expected one of %s", route, strings.Join(allRoutes, ", "))
+			} else if route == RouteAdmin && !server.Admin.Enabled {
+				problems.add(routePath, "admin route requires admin.enabled")
+			}
 		}
-		if len(clientCfg.Crypto.AESKey) != 64 {
-			return fmt.Errorf("invalid AES key length for config %s", hash)
+
+		if l.TLS {
+			usesTLS = true
+			if !server.TLS.Enabled {
+				problems.add(path+".tls", "listener TLS requires tls.enabled")
+			}
 		}
-		if len(clientCfg.Crypto.AESIVSeed) != 16 {
-			return fmt.Errorf("invalid AES IV seed length for config %s", hash)
+		if l.SocketMode != "" {
+			if l.Network != NetworkUnix {
+				problems.add(path+".socket_mode", "socket mode only applies to unix listeners")
+			} else if _, err := l.SocketFileMode(); err != nil {
+				problems.add(path+".socket_mode", "%v", err)
+			}
 		}
 	}
+	if server.TLS.Enabled && !usesTLS {
+		problems.add("tls.enabled", "TLS enabled but no listener has tls set")
+	}
+}
 
-	return nil
+// checkClientCertProfiles 检查客户端证书对应的令牌配置
+func checkClientCertProfiles(server *ServerConfig, problems *Problems) {
+	names := make(map[string]bool)
+	for i, profile := range server.TLS.ClientAuth.Profiles {
+		path := fmt.Sprintf("tls.client_auth.profiles[%d]", i)
+		if profile.Name == "" {
+			problems.add(path+".name", "client certificate profile has no name")
+		} else if names[profile.Name] {
+			problems.add(path+".name", "duplicate client certificate profile: %s", profile.Name)
+		}
+		names[profile.Name] = true
+
+		if len(profile.Match) == 0 {
+			problems.add(path+".match", "client certificate profile matches no identity")
+		}
+		for j, identity := range profile.Match {
+			kind, value, _ := strings.Cut(identity, ":")
+			if !slices.Contains(clientCertIdentityKinds, kind) || value == "" {
+				problems.add(fmt.Sprintf("%s.match[%d]", path, j),
+					"invalid certificate identity %q, expected one of %s followed by a value",
+					identity, strings.Join(clientCertIdentityKinds, ":, ")+":")
+			}
+		}
+
+		alias, ok := server.Admin.KeyAliases[profile.KeyAlias]
+		if !ok {
+			problems.add(path+".key_alias", "unknown key alias: %s", profile.KeyAlias)
+		} else if profile.Provider == "" && alias.Provider == "" {
+			problems.add(path+".provider", "client certificate profile has no provider")
+		}
+		if profile.MaxCalls < 0 {
+			problems.add(path+".max_calls", "max calls must not be negative")
+		}
+	}
+}
+
+// CheckClientConfig 检查单个客户端配置的取值，返回全部问题
+func CheckClientConfig(clientCfg *ClientConfig) Problems {
+	var problems Problems
+
+	// 验证服务器配置
+	if clientCfg.Server.Port <= 0 {
+		problems.add("server.port", "invalid client server port")
+	}
+
+	// 验证加密配置
+	if clientCfg.Crypto.Method != "aes" {
+		problems.add("crypto.method", "unsupported encryption method: %s", clientCfg.Crypto.Method)
+	}
+	if len(clientCfg.Crypto.AESKey) != 64 {
+		problems.add("crypto.aes_key", "invalid AES key length")
+	}
+	if len(clientCfg.Crypto.AESIVSeed) != 16 {
+		problems.add("crypto.aes_iv_seed", "invalid AES IV seed length")
+	}
+
+	// 验证轮换保留的旧密钥
+	for i, key := range clientCfg.Crypto.PreviousKeys {
+		path := fmt.Sprintf("crypto.previous_keys[%d]", i)
+		if len(key.AESKey) != 64 || len(key.AESIVSeed) != 16 {
+			problems.add(path, "invalid previous key for generation %d", key.Generation)
+		}
+		if key.RetireAt.IsZero() {
+			problems.add(path+".retire_at", "previous key for generation %d has no retire_at", key.Generation)
+		}
+		if key.Generation >= clientCfg.Crypto.Generation {
+			problems.add(path+".generation", "previous key generation %d must be lower than current generation %d",
+				key.Generation, clientCfg.Crypto.Generation)
+		}
+	}
+	return problems
+}
+
+// CheckConfigFiles 检查服务器配置文件和客户端配置文件（或目录中的 .rai 文件），
+// 返回每个文件中的全部问题，用于 --check-config
+func CheckConfigFiles(serverConfigPath, clientConfigPath string) Problems {
+	server := &ServerConfig{}
+	problems := checkConfigFile(serverConfigPath, serverSchema, server, func() Problems {
+		if err := applyEnvOverrides(server); err != nil {
+			return Problems{{Message: err.Error()}}
+		}
+		return CheckServerConfig(server)
+	})
+
+	clientFiles := []string{clientConfigPath}
+	if info, err := os.Stat(clientConfigPath); err == nil && info.IsDir() {
+		clientFiles, _ = filepath.Glob(filepath.Join(clientConfigPath, "*.rai"))
+		if len(clientFiles) == 0 {
+			problems = append(problems, Problem{File: clientConfigPath, Message: "no client configurations found"})
+		}
+	}
+	for _, path := range clientFiles {
+		client := &ClientConfig{}
+		problems = append(problems, checkConfigFile(path, clientSchema, client, func() Problems {
+			return CheckClientConfig(client)
+		})...)
+	}
+	return problems
 }
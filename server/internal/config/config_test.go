@@ -0,0 +1,190 @@
+package config
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestCheckServerConfigClientAuth(t *testing.T) {
+	server := &ServerConfig{}
+	server.Server.Port = 8840
+	server.Server.ReadTimeout, server.Server.WriteTimeout = 30, 30
+	server.RateLimit.RequestsPerSecond, server.RateLimit.Burst = 10, 10
+	server.Admin.KeyAliases = map[string]KeyAlias{"billing": {APIKey: "sk-billing"}}
+	server.TLS.ClientAuth.Enabled = true
+	server.TLS.ClientAuth.Profiles = []ClientCertProfile{
+		{Name: "billing", Match: []string{"uri:spiffe://prod/billing"}, KeyAlias: "billing", Provider: "openai"},
+		{Name: "billing", Match: []string{"subject:billing", "dns:"}, KeyAlias: "missing", MaxCalls: -1},
+		{Name: "reports", Match: []string{"cn:reports"}, KeyAlias: "billing"},
+	}
+
+	var paths []string
+	for _, problem := range CheckServerConfig(server) {
+		paths = append(paths, problem.Path)
+	}
+	want := []string{
+		"tls.client_auth.enabled",
+		"tls.client_auth.ca_file",
+		"tls.client_auth.profiles[1].name",
+		"tls.client_auth.profiles[1].match[0]",
+		"tls.client_auth.profiles[1].match[1]",
+		"tls.client_auth.profiles[1].key_alias",
+		"tls.client_auth.profiles[1].max_calls",
+		"tls.client_auth.profiles[2].provider",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected problems at %v, got %v", want, paths)
+	}
+}
+
+func TestListenerConfigs(t *testing.T) {
+	server := &ServerConfig{}
+	server.Server.Host, server.Server.Port = "0.0.0.0", 8840
+	server.Admin.Enabled = true
+	server.Admin.Listen = "127.0.0.1:8841"
+	server.TLS.Enabled = true
+
+	// 没有配置 listeners 时兼容 server.port 和 admin.listen
+	want := []Listener{
+		{Name: "server", Network: NetworkTCP, Address: "0.0.0.0:8840", Routes: []string{RouteAPI, RouteHealth}, TLS: true},
+		{Name: "admin", Network: NetworkTCP, Address: "127.0.0.1:8841", Routes: []string{RouteAdmin}},
+	}
+	if got := server.ListenerConfigs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	server.Admin.Listen = ""
+	server.Listeners = []Listener{
+		{Address: ":8443", Routes: []string{RouteAPI}, TLS: true},
+		{Network: NetworkUnix, Address: "/run/relayapi/admin.sock", Routes: []string{RouteAdmin, RouteHealth}, SocketMode: "0660"},
+	}
+	listeners := server.ListenerConfigs()
+	if listeners[0].Network != NetworkTCP || listeners[0].Name != "tcp::8443" {
+		t.Errorf("Expected tcp defaults, got %+v", listeners[0])
+	}
+	if listeners[1].HasRoute(RouteAPI) || !listeners[1].HasRoute(RouteHealth) {
+		t.Errorf("Unexpected routes for %+v", listeners[1])
+	}
+	// 管理接口只在 unix 套接字上提供，不需要管理令牌
+	if problems := CheckServerConfig(server); hasProblem(problems, "admin.token") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+
+	// 管理接口在非回环地址的独立 TCP 监听上提供时仍需要管理令牌
+	server.Listeners = []Listener{
+		{Address: ":8443", Routes: []string{RouteAPI}, TLS: true},
+		{Address: "127.0.0.1:8841", Routes: []string{RouteAdmin}},
+		{Address: "[::1]:8842", Routes: []string{RouteAdmin}},
+		{Address: "localhost:8843", Routes: []string{RouteAdmin}},
+	}
+	if problems := CheckServerConfig(server); hasProblem(problems, "admin.token") {
+		t.Errorf("Unexpected problems for loopback admin listeners: %v", problems)
+	}
+	for _, l := range []Listener{
+		{Address: "0.0.0.0:8841", Routes: []string{RouteAdmin}},
+		{Address: ":8841", Routes: []string{RouteAdmin}},
+		{Network: NetworkSystemd, Routes: []string{RouteAdmin}},
+	} {
+		server.Listeners = []Listener{{Network: NetworkUnix, Address: "/run/relayapi/api.sock", Routes: []string{RouteAPI}}, l}
+		if !hasProblem(CheckServerConfig(server), "admin.token") {
+			t.Errorf("Expected admin.token to be required for %+v", l)
+		}
+	}
+	server.Listeners = nil
+	server.Admin.Listen = "0.0.0.0:8841"
+	if !hasProblem(CheckServerConfig(server), "admin.token") {
+		t.Error("Expected admin.token to be required for a public admin.listen")
+	}
+	server.Admin.Token = "change-me"
+	if hasProblem(CheckServerConfig(server), "admin.token") {
+		t.Error("Unexpected admin.token problem with a token set")
+	}
+
+	server.Admin.Enabled = false
+	server.Admin.Listen = "127.0.0.1:8841"
+	server.TLS.Enabled = false
+	server.Listeners = []Listener{
+		{Address: ":8443", Routes: []string{RouteAPI}, TLS: true},
+		{Network: NetworkUnix, Address: "/run/relayapi/admin.sock", Routes: []string{RouteAdmin, RouteHealth}, SocketMode: "0660"},
+	}
+	server.Listeners = append(server.Listeners,
+		Listener{Network: "udp", Routes: []string{"metrics"}, SocketMode: "0660"},
+		Listener{Network: NetworkUnix, Address: "/run/relayapi/api.sock", SocketMode: "rw"},
+	)
+	for _, path := range []string{
+		"admin.listen",
+		"listeners[0].tls",
+		"listeners[1].routes[0]",
+		"listeners[2].network",
+		"listeners[2].routes[0]",
+		"listeners[2].socket_mode",
+		"listeners[3].socket_mode",
+	} {
+		if !hasProblem(CheckServerConfig(server), path) {
+			t.Errorf("Expected a problem at %s", path)
+		}
+	}
+}
+
+func TestRedirectPort(t *testing.T) {
+	server := &ServerConfig{}
+	server.Server.Host, server.Server.Port = "0.0.0.0", 8840
+	server.TLS.Enabled = true
+	if port, err := server.RedirectPort(); err != nil || port != 8840 {
+		t.Errorf("Expected server.port without listeners, got %d, %v", port, err)
+	}
+
+	// 配置 listeners 后使用提供 api 路由的 TLS 监听的端口，而不是 server.port
+	server.Listeners = []Listener{
+		{Address: ":8080", Routes: []string{RouteHealth}},
+		{Address: ":443", Routes: []string{RouteAPI, RouteHealth}, TLS: true},
+		{Address: "127.0.0.1:443", Routes: []string{RouteAPI}, TLS: true},
+	}
+	if port, err := server.RedirectPort(); err != nil || port != 443 {
+		t.Errorf("Expected TLS listener port 443, got %d, %v", port, err)
+	}
+
+	server.TLS.RedirectHTTP = ":80"
+	for _, listeners := range [][]Listener{
+		{{Address: ":443", Routes: []string{RouteAPI}, TLS: true}, {Address: ":8443", Routes: []string{RouteAPI}, TLS: true}},
+		{{Network: NetworkUnix, Address: "/run/relayapi/api.sock", Routes: []string{RouteAPI}, TLS: true}},
+		{{Address: ":443", Routes: []string{RouteHealth}, TLS: true}},
+	} {
+		server.Listeners = listeners
+		if !hasProblem(CheckServerConfig(server), "tls.redirect_http") {
+			t.Errorf("Expected an ambiguous redirect port problem for %+v", listeners)
+		}
+	}
+
+	// systemd 套接字的端口在启动时根据实际打开的地址确定
+	server.Listeners = []Listener{{Network: NetworkSystemd, Address: "relayapi", Routes: []string{RouteAPI}, TLS: true}}
+	if hasProblem(CheckServerConfig(server), "tls.redirect_http") {
+		t.Error("Unexpected redirect port problem for a systemd listener")
+	}
+	listeners := server.ListenerConfigs()
+	if port, err := RedirectPortOf(listeners, []net.Addr{&net.TCPAddr{Port: 9443}}); err != nil || port != 9443 {
+		t.Errorf("Expected the port of the passed socket, got %d, %v", port, err)
+	}
+	if _, err := RedirectPortOf(listeners, []net.Addr{&net.UnixAddr{Name: "/run/relayapi.sock", Net: "unix"}}); err == nil {
+		t.Error("Expected a passed unix socket to be rejected")
+	}
+
+	// 没有配置 listeners 时主监听被 systemd 套接字替换，使用实际的端口而不是 server.port
+	server.Listeners = nil
+	listeners = server.ListenerConfigs()
+	listeners[0].Network, listeners[0].Address = NetworkSystemd, ""
+	if port, err := RedirectPortOf(listeners, []net.Addr{&net.TCPAddr{Port: 9443}}); err != nil || port != 9443 {
+		t.Errorf("Expected the port of the passed socket instead of server.port, got %d, %v", port, err)
+	}
+}
+
+// hasProblem 判断是否有指定字段的问题
+func hasProblem(problems Problems, path string) bool {
+	for _, problem := range problems {
+		if problem.Path == path {
+			return true
+		}
+	}
+	return false
+}
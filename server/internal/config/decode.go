@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件格式，按扩展名识别，其他扩展名（包括 .rai）按 JSON 解析
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FormatOf 根据文件扩展名返回配置文件格式
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// parseConfigTree 解析配置文件为 JSON 形式的值（数字为 json.Number），
+// 并替换其中的 ${VAR} 和 *_file 字段。无法读取的 *_file 作为 Problems 返回，其余字段仍然可用
+func parseConfigTree(path string, data []byte, schema *Schema) (interface{}, Problems, error) {
+	format := FormatOf(path)
+	data, err := expandEnv(data, format == FormatJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// YAML 和 TOML 先转换为 JSON，使用与 JSON 相同的校验和反序列化
+	switch format {
+	case FormatYAML, FormatTOML:
+		var parsed interface{}
+		if format == FormatYAML {
+			err = yaml.Unmarshal(data, &parsed)
+		} else {
+			err = toml.Unmarshal(data, &parsed)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if parsed == nil {
+			// 空的 YAML 文件
+			parsed = map[string]interface{}{}
+		}
+		if data, err = json.Marshal(normalizeYAML(parsed)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // 保持大整数的精度，并区分整数和小数
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, nil, err
+	}
+
+	var problems Problems
+	resolveSecretFiles(schema, tree, "", &problems)
+	return tree, problems, nil
+}
+
+// normalizeYAML 将 YAML 中非字符串键的 map 转换为字符串键
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+	}
+	return value
+}
+
+// decodeConfigFile 解析配置文件并按 Schema 严格校验，未知字段和类型错误会全部报告
+func decodeConfigFile(path string, data []byte, schema *Schema, v interface{}) error {
+	tree, problems, err := parseConfigTree(path, data, schema)
+	if err != nil {
+		return err
+	}
+	if problems = append(problems, schema.Validate(tree)...); len(problems) > 0 {
+		return problems
+	}
+	return decodeTree(tree, v)
+}
+
+// decodeTree 将解析后的值反序列化到配置结构体
+func decodeTree(tree interface{}, v interface{}) error {
+	encoded, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, v)
+}
+
+// checkConfigFile 读取并检查一个配置文件，返回全部问题而不是在第一个问题处停止。
+// finish 在反序列化后执行，用于检查字段的取值
+func checkConfigFile(path string, schema *Schema, v interface{}, finish func() Problems) Problems {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Problems{{File: path, Message: err.Error()}}
+	}
+	tree, problems, err := parseConfigTree(path, data, schema)
+	if err != nil {
+		return Problems{{File: path, Message: err.Error()}}
+	}
+
+	problems = append(problems, schema.Validate(tree)...)
+	// 类型错误已经由 Schema 报告，其余字段仍然反序列化以便检查取值
+	_ = decodeTree(tree, v)
+
+	reported := make(map[string]bool, len(problems))
+	for _, problem := range problems {
+		reported[problem.Path] = true
+		// 无法读取的 xxx_file 不再重复报告 xxx 为空
+		reported[strings.TrimSuffix(problem.Path, fileSuffix)] = true
+	}
+	for _, problem := range finish() {
+		if !reported[problem.Path] {
+			problems = append(problems, problem)
+		}
+	}
+	return problems.inFile(path)
+}
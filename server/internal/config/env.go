@@ -0,0 +1,196 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix 覆盖服务器配置的环境变量前缀，如 RELAYAPI_LOG_DATABASE_CONNECTION_STRING
+const EnvPrefix = "RELAYAPI_"
+
+// fileSuffix 从文件读取配置值的后缀，如 "connection_string_file" 或 RELAYAPI_..._FILE
+const fileSuffix = "_file"
+
+// envPattern 匹配配置文件中的 ${VAR} 和 ${VAR:-default}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv 将 ${VAR} 替换为环境变量的值，变量未设置且没有默认值时返回错误。
+// escapeJSON 为 true 时替换的值按 JSON 字符串转义，可以安全地放在字符串中
+func expandEnv(data []byte, escapeJSON bool) ([]byte, error) {
+	var missing []string
+	expanded := envPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		groups := envPattern.FindSubmatch(match)
+		value, ok := os.LookupEnv(string(groups[1]))
+		if !ok {
+			if groups[2] == nil {
+				missing = append(missing, string(groups[1]))
+				return match
+			}
+			value = string(groups[3])
+		}
+		if !escapeJSON {
+			return []byte(value)
+		}
+		quoted, _ := json.Marshal(value)
+		return quoted[1 : len(quoted)-1]
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("undefined environment variables: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// resolveSecretFiles 将 "xxx_file": "/path" 替换为 "xxx": 文件内容，用于读取挂载的密钥文件。
+// 按 schema 递归处理解析后的配置，本身以 _file 结尾的字段（如 tls.cert_file）保持不变。
+// 无法读取的文件和同时设置的 xxx、xxx_file 全部记录到 problems，字段路径为完整路径
+func resolveSecretFiles(schema *Schema, value interface{}, path string, problems *Problems) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property := schema.property(key)
+			file, isString := v[key].(string)
+			if name := strings.TrimSuffix(key, fileSuffix); name != key && isString && !schema.hasProperty(key) {
+				// 出错的字段同样删除，避免再被 Schema 报告为未知字段
+				delete(v, key)
+				if _, exists := v[name]; exists {
+					problems.add(joinPath(path, key), "both %s and %s are set", name, key)
+					continue
+				}
+				secret, err := readSecretFile(file)
+				if err != nil {
+					problems.add(joinPath(path, key), "%v", err)
+					continue
+				}
+				v[name] = secret
+				continue
+			}
+			resolveSecretFiles(property, v[key], joinPath(path, key), problems)
+		}
+	case []interface{}:
+		var items *Schema
+		if schema != nil {
+			items = schema.Items
+		}
+		for i, item := range v {
+			resolveSecretFiles(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
+
+// readSecretFile 读取密钥文件，去掉末尾的换行符
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %v", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// applyEnvOverrides 使用 RELAYAPI_* 环境变量覆盖服务器配置。变量名由 JSON 字段路径转为大写并以下划线连接，
+// 如 log.database.connection_string 对应 RELAYAPI_LOG_DATABASE_CONNECTION_STRING，
+// 加上 _FILE 后缀时从该文件读取值
+func applyEnvOverrides(server *ServerConfig) error {
+	return applyEnvOverridesTo(reflect.ValueOf(server).Elem(), strings.TrimSuffix(EnvPrefix, "_"))
+}
+
+// applyEnvOverridesTo 递归遍历结构体字段，结构体以外的字段都可以被覆盖
+func applyEnvOverridesTo(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnvOverridesTo(v.Field(i), name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok, err := lookupEnv(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := setFieldValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// lookupEnv 读取 NAME 或 NAME_FILE 指向的文件，两者不能同时设置
+func lookupEnv(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+	path, fromFile := os.LookupEnv(name + strings.ToUpper(fileSuffix))
+	switch {
+	case ok && fromFile:
+		return "", false, fmt.Errorf("both %s and %s%s are set", name, name, strings.ToUpper(fileSuffix))
+	case fromFile:
+		secret, err := readSecretFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("%s%s: %v", name, strings.ToUpper(fileSuffix), err)
+		}
+		return secret, true, nil
+	}
+	return value, ok, nil
+}
+
+// setFieldValue 将环境变量的字符串值写入字段。列表可以用逗号分隔或 JSON 数组，map 使用 JSON 对象
+func setFieldValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			items := []string{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+			return nil
+		}
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	default:
+		// map 等其他类型使用 JSON，替换而不是合并配置文件中的值
+		field.Set(reflect.Zero(field.Type()))
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
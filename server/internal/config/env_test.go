@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadServerConfigEnv(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secret, []byte("postgres://user:p\"ss@db/logs\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	callback := filepath.Join(dir, "callback")
+	if err := os.WriteFile(callback, []byte("https://logs.example.com/hook"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "config.json")
+	data := `{
+		"server": {"host": "${RELAYAPI_TEST_HOST}", "port": ${RELAYAPI_TEST_PORT:-8840}, "read_timeout": 30, "write_timeout": 30},
+		"log": {
+			"database": {"enabled": true, "type": "postgres", "connection_string_file": "` + secret + `"},
+			"web": {"enabled": true, "callback_url": "http://placeholder"}
+		},
+		"rate_limit": {"requests_per_second": 10, "burst": 20},
+		"proxy": {"body": {"route_max_size": {"/audio": 100}}}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("RELAYAPI_TEST_HOST", "0.0.0.0")
+	t.Setenv("RELAYAPI_RATE_LIMIT_IP_LIMIT_BURST", "7")
+	t.Setenv("RELAYAPI_LOG_WEB_CALLBACK_URL_FILE", callback)
+	t.Setenv("RELAYAPI_PROXY_HEADERS_FORWARD", "Authorization, Content-Type")
+	t.Setenv("RELAYAPI_PROXY_BODY_ROUTE_MAX_SIZE", `{"/files": 200}`)
+	t.Setenv("RELAYAPI_CACHE_ENABLED", "true")
+
+	server, err := LoadServerConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.Server.Host != "0.0.0.0" || server.Server.Port != 8840 {
+		t.Errorf("Unexpected interpolated server %s:%d", server.Server.Host, server.Server.Port)
+	}
+	if server.Log.Database.ConnectionString != `postgres://user:p"ss@db/logs` {
+		t.Errorf("Unexpected connection string from file: %q", server.Log.Database.ConnectionString)
+	}
+	if server.Log.Web.CallbackURL != "https://logs.example.com/hook" {
+		t.Errorf("Unexpected callback URL: %s", server.Log.Web.CallbackURL)
+	}
+	if server.RateLimit.IPLimit.Burst != 7 || server.RateLimit.Burst != 20 {
+		t.Errorf("Unexpected rate limit: %+v", server.RateLimit)
+	}
+	if forward := server.Proxy.Headers.Forward; len(forward) != 2 || forward[1] != "Content-Type" {
+		t.Errorf("Unexpected forward headers: %v", forward)
+	}
+	if sizes := server.Proxy.Body.RouteMaxSize; len(sizes) != 1 || sizes["/files"] != 200 {
+		t.Errorf("Expected map override to replace the file value, got %v", sizes)
+	}
+	if !server.Cache.Enabled {
+		t.Error("Expected cache to be enabled by environment variable")
+	}
+}
+
+func TestLoadServerConfigEnvErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"server": {"host": "${RELAYAPI_TEST_UNDEFINED}"}}`)
+	if _, err := LoadServerConfig(path); err == nil {
+		t.Error("Expected undefined variable to be rejected")
+	}
+
+	write(`{"log": {"database": {"connection_string_file": "` + filepath.Join(dir, "missing") + `"}}}`)
+	if _, err := LoadServerConfig(path); err == nil {
+		t.Error("Expected missing secret file to be rejected")
+	}
+
+	write(`{}`)
+	t.Setenv("RELAYAPI_SERVER_PORT", "not-a-number")
+	if _, err := LoadServerConfig(path); err == nil {
+		t.Error("Expected invalid integer override to be rejected")
+	}
+}
+
+func TestClientConfigInterpolation(t *testing.T) {
+	dir := t.TempDir()
+	client := newClient(t)
+	keyFile := filepath.Join(dir, "aes_key")
+	if err := os.WriteFile(keyFile, []byte(client.Crypto.AESKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RELAYAPI_TEST_SEED", client.Crypto.AESIVSeed)
+
+	path := filepath.Join(dir, "client.rai")
+	data := `{"version": "1.0.0", "server": {"host": "http://localhost", "port": 8840},
+		"crypto": {"method": "aes", "aes_key_file": "` + keyFile + `", "aes_iv_seed": "${RELAYAPI_TEST_SEED}"}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := ReadClientConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Crypto.AESKey != client.Crypto.AESKey || loaded.Crypto.AESIVSeed != client.Crypto.AESIVSeed {
+		t.Error("Expected crypto settings to be resolved from file and environment")
+	}
+	if err := ValidateClientConfig(&loaded); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckConfigFilesSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	serverPath := filepath.Join(dir, "config.yaml")
+	data := `
+server:
+  port: 8840
+  read_timeout: 30
+  write_timeout: 30
+  debg: true
+admin:
+  token_file: ` + filepath.Join(dir, "missing-token") + `
+log:
+  database:
+    enabled: true
+    type: postgres
+    connection_string_file: ` + filepath.Join(dir, "missing-dsn") + `
+  web:
+    enabled: true
+    callback_url: http://example.com/log
+    callback_url_file: ` + filepath.Join(dir, "callback") + `
+rate_limit:
+  requests_per_second: 20
+  burst: 40
+`
+	if err := os.WriteFile(serverPath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	clientPath := filepath.Join(dir, "default.rai")
+	writeClient(t, clientPath, newClient(t))
+
+	// 所有密钥文件的问题都带有完整路径，并与其他问题一起报告
+	problems := CheckConfigFiles(serverPath, clientPath)
+	for _, path := range []string{
+		"admin.token_file",
+		"log.database.connection_string_file",
+		"log.web.callback_url_file",
+		"server.debg",
+	} {
+		if !hasProblem(problems, path) {
+			t.Errorf("Expected a problem at %s, got %v", path, problems)
+		}
+	}
+	if hasProblem(problems, "log.database.connection_string") {
+		t.Errorf("Expected the unreadable file not to be reported again, got %v", problems)
+	}
+
+	if _, err := LoadServerConfig(serverPath); err == nil || !strings.Contains(err.Error(), "log.database.connection_string_file") {
+		t.Errorf("Expected the load error to name the full path, got %v", err)
+	}
+}
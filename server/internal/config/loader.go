@@ -6,10 +6,15 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
@@ -23,12 +28,34 @@ type ClientConfig struct {
 		BasePath string `json:"base_path"`
 	} `json:"server"`
 	Crypto struct {
-		Method    string `json:"method"`
-		AESKey    string `json:"aes_key"`
-		AESIVSeed string `json:"aes_iv_seed"`
+		Method     string `json:"method"`
+		AESKey     string `json:"aes_key"`
+		AESIVSeed  string `json:"aes_iv_seed"`
+		Generation int    `json:"generation,omitempty"` // 密钥代数，每次轮换加一
+
+		// 轮换前的旧密钥，在退役时间之前仍可用于解密令牌
+		PreviousKeys []PreviousKey `json:"previous_keys,omitempty"`
 	} `json:"crypto"`
 }
 
+// PreviousKey 轮换后保留的旧密钥
+type PreviousKey struct {
+	AESKey     string    `json:"aes_key"`
+	AESIVSeed  string    `json:"aes_iv_seed"`
+	Generation int       `json:"generation"`
+	RetireAt   time.Time `json:"retire_at"` // 退役时间，之后使用该密钥的令牌失效
+}
+
+// Active 判断旧密钥在 now 时是否仍可用于解密
+func (k *PreviousKey) Active(now time.Time) bool {
+	return now.Before(k.RetireAt)
+}
+
+// Hash 返回旧密钥对应的配置 hash，用旧密钥签发的令牌携带的是这个 hash
+func (k *PreviousKey) Hash(method string) string {
+	return hashCryptoParams(method, k.AESKey, k.AESIVSeed)
+}
+
 // ServerConfig 服务器配置结构
 type ServerConfig struct {
 	Server struct {
@@ -38,7 +65,14 @@ type ServerConfig struct {
 		WriteTimeout   int    `json:"write_timeout"`
 		MaxHeaderBytes int    `json:"max_header_bytes"`
 		Debug          bool   `json:"debug"`
+
+		// 关闭时等待进行中的请求（包括流式响应）完成的最长秒数，0 表示使用默认值
+		ShutdownTimeout int `json:"shutdown_timeout"`
 	} `json:"server"`
+
+	// 监听地址及其提供的路由组，为空时监听 server.host:server.port（以及 admin.listen）
+	Listeners []Listener `json:"listeners"`
+
 	Log struct {
 		Console  bool `json:"console"`
 		Database struct {
@@ -63,32 +97,366 @@ type ServerConfig struct {
 			Burst             int `json:"burst"`
 		} `json:"ip_limit"`
 	} `json:"rate_limit"`
+	Proxy struct {
+		Headers struct {
+			Forward       []string `json:"forward"`         // 允许转发的请求头，为空时转发除 deny 之外的全部请求头
+			Deny          []string `json:"deny"`            // 额外禁止转发的请求头
+			ResponseDeny  []string `json:"response_deny"`   // 禁止返回给客户端的响应头
+			XForwardedFor bool     `json:"x_forwarded_for"` // 是否追加 X-Forwarded-For
+			Via           bool     `json:"via"`             // 是否追加 Via
+		} `json:"headers"`
+		Body struct {
+			MaxSize          int64            `json:"max_size"`          // 请求体最大字节数，0 表示不限制
+			RouteMaxSize     map[string]int64 `json:"route_max_size"`    // 按路径前缀覆盖 max_size
+			ProcessThreshold int64            `json:"process_threshold"` // 不超过该大小的 JSON 请求体才会被缓冲和修改
+		} `json:"body"`
+		Timeouts struct {
+			FirstByte int `json:"first_byte"` // 等待上游响应头的最长秒数，0 表示不限制
+			Idle      int `json:"idle"`       // 两次收到上游数据之间的最长秒数，0 表示不限制
+			Stream    int `json:"stream"`     // 流式响应的最长持续秒数，0 表示不限制
+			Keepalive int `json:"keepalive"`  // 流式响应空闲时发送 ": keepalive" 注释的间隔秒数，0 表示不发送
+		} `json:"timeouts"`
+		Coalesce struct {
+			Enabled         bool  `json:"enabled"`           // 是否合并相同的并发非流式请求
+			MaxResponseSize int64 `json:"max_response_size"` // 可共享的最大响应字节数，0 表示使用 process_threshold
+		} `json:"coalesce"`
+	} `json:"proxy"`
+	Cache struct {
+		Enabled      bool           `json:"enabled"`        // 是否启用响应缓存
+		Backend      string         `json:"backend"`        // 缓存后端：memory 或 sqlite
+		MaxEntries   int            `json:"max_entries"`    // 内存缓存的最大条目数
+		MaxEntrySize int64          `json:"max_entry_size"` // 单条缓存响应的最大字节数
+		SQLitePath   string         `json:"sqlite_path"`    // sqlite 后端的数据库文件
+		TTL          int            `json:"ttl"`            // 默认缓存秒数，0 表示只缓存 route_ttl 中的路由
+		RouteTTL     map[string]int `json:"route_ttl"`      // 按路径前缀覆盖 ttl，0 表示不缓存该路由
+		Semantic     struct {
+			Enabled    bool    `json:"enabled"`     // 是否启用语义缓存，还需要在令牌 ext_info 中设置 semantic_cache
+			Threshold  float64 `json:"threshold"`   // 命中所需的最小余弦相似度
+			Provider   string  `json:"provider"`    // 计算嵌入向量的提供商，为空时使用令牌的提供商
+			Model      string  `json:"model"`       // 嵌入模型
+			APIKey     string  `json:"api_key"`     // 嵌入接口的 API Key，为空时使用令牌的 API Key
+			MaxEntries int     `json:"max_entries"` // 向量索引的最大条目数
+			TTL        int     `json:"ttl"`         // 语义缓存的秒数
+		} `json:"semantic"`
+	} `json:"cache"`
+	Admin struct {
+		Enabled bool   `json:"enabled"` // 是否启用 /admin 管理接口
+		Token   string `json:"token"`   // 管理令牌，通过 Authorization: Bearer 或 X-Admin-Token 传递
+		Listen  string `json:"listen"`  // 管理接口的独立监听地址，为空时与 API 共用端口
+
+		// 签发令牌时可用的 API Key 别名，后端只需传别名，无需持有真实的 API Key
+		KeyAliases map[string]KeyAlias `json:"key_aliases"`
+	} `json:"admin"`
+	TLS struct {
+		Enabled      bool   `json:"enabled"`       // 是否使用 HTTPS 提供 API
+		CertFile     string `json:"cert_file"`     // 证书文件（PEM），文件更新时自动重新加载
+		KeyFile      string `json:"key_file"`      // 私钥文件（PEM）
+		MinVersion   string `json:"min_version"`   // 最低 TLS 版本：1.2 或 1.3，默认 1.2
+		DisableHTTP2 bool   `json:"disable_http2"` // 是否禁用 HTTP/2
+		RedirectHTTP string `json:"redirect_http"` // HTTP 重定向监听地址（如 ":80"），为空时不监听
+		ACME         struct {
+			Enabled      bool     `json:"enabled"`       // 是否自动申请和续期证书，启用时忽略 cert_file 和 key_file
+			Domains      []string `json:"domains"`       // 允许申请证书的域名
+			Email        string   `json:"email"`         // 账户联系邮箱
+			CacheDir     string   `json:"cache_dir"`     // 账户密钥和证书的缓存目录
+			DirectoryURL string   `json:"directory_url"` // ACME 目录地址，为空时使用 Let's Encrypt，可指向 Pebble 等测试 CA
+			CAFile       string   `json:"ca_file"`       // 访问 ACME 目录时信任的 CA 证书（测试 CA 使用自签名证书时）
+		} `json:"acme"`
+		ClientAuth struct {
+			Enabled  bool   `json:"enabled"`  // 是否校验客户端证书（mTLS）
+			CAFile   string `json:"ca_file"`  // 签发客户端证书的 CA（PEM）
+			Required bool   `json:"required"` // 是否拒绝没有客户端证书的连接，为 false 时仍可使用令牌访问
+
+			// 客户端证书对应的服务端令牌配置，按顺序使用第一个匹配的配置
+			Profiles []ClientCertProfile `json:"profiles"`
+		} `json:"client_auth"`
+	} `json:"tls"`
+}
+
+// KeyAlias 签发令牌时使用的 API Key 别名
+type KeyAlias struct {
+	Provider string `json:"provider"` // 为空时使用请求中的提供商
+	APIKey   string `json:"api_key"`
+}
+
+// Listener 一个监听地址及其提供的路由组
+type Listener struct {
+	Name       string   `json:"name"`        // 日志中显示的名称，默认使用地址
+	Network    string   `json:"network"`     // tcp（默认）、unix 或 systemd
+	Address    string   `json:"address"`     // tcp 为 host:port，unix 为套接字文件路径，systemd 为 FileDescriptorName（为空时使用第一个传入的套接字）
+	Routes     []string `json:"routes"`      // 提供的路由组：api、admin、health，为空时提供全部
+	TLS        bool     `json:"tls"`         // 是否使用 tls 中的证书提供 HTTPS
+	SocketMode string   `json:"socket_mode"` // unix 套接字文件的权限（八进制），如 0660
+}
+
+// 监听的网络类型
+const (
+	NetworkTCP     = "tcp"
+	NetworkUnix    = "unix"
+	NetworkSystemd = "systemd" // systemd 套接字激活传入的套接字
+)
+
+// 监听可以提供的路由组
+const (
+	RouteAPI    = "api"    // /relayapi
+	RouteAdmin  = "admin"  // /admin 和 /relayapi-admin，需要启用 admin
+	RouteHealth = "health" // /health
+)
+
+// allRoutes 未指定路由组时提供的全部路由组
+var allRoutes = []string{RouteAPI, RouteAdmin, RouteHealth}
+
+// HasRoute 判断监听是否提供该路由组
+func (l *Listener) HasRoute(route string) bool {
+	return len(l.Routes) == 0 || slices.Contains(l.Routes, route)
+}
+
+// IsLocal 判断监听是否只能从本机访问：unix 套接字或回环地址上的 TCP 监听
+func (l *Listener) IsLocal() bool {
+	switch l.Network {
+	case NetworkUnix:
+		return true
+	case NetworkTCP:
+		host, _, err := net.SplitHostPort(l.Address)
+		if err != nil {
+			return false
+		}
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
+
+// SocketFileMode 解析 unix 套接字文件的权限，未设置时返回 0
+func (l *Listener) SocketFileMode() (os.FileMode, error) {
+	if l.SocketMode == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(l.SocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socket mode %q, expected octal permissions such as 0660", l.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// ListenerConfigs 返回要打开的监听。没有配置 listeners 时兼容旧配置：
+// 主监听为 server.host:server.port，设置了 admin.listen 时管理接口使用独立的 HTTP 监听
+func (s *ServerConfig) ListenerConfigs() []Listener {
+	if len(s.Listeners) > 0 {
+		listeners := make([]Listener, len(s.Listeners))
+		for i, l := range s.Listeners {
+			if l.Network == "" {
+				l.Network = NetworkTCP
+			}
+			if l.Name == "" {
+				l.Name = l.Network + ":" + l.Address
+			}
+			listeners[i] = l
+		}
+		return listeners
+	}
+
+	main := Listener{
+		Name:    "server",
+		Network: NetworkTCP,
+		Address: fmt.Sprintf("%s:%d", s.Server.Host, s.Server.Port),
+		Routes:  []string{RouteAPI, RouteHealth},
+		TLS:     s.TLS.Enabled,
+	}
+	if s.Admin.Enabled && s.Admin.Listen == "" {
+		main.Routes = append(main.Routes, RouteAdmin)
+	}
+	listeners := []Listener{main}
+	if s.Admin.Enabled && s.Admin.Listen != "" {
+		listeners = append(listeners, Listener{
+			Name:    "admin",
+			Network: NetworkTCP,
+			Address: s.Admin.Listen,
+			Routes:  []string{RouteAdmin},
+		})
+	}
+	return listeners
+}
+
+// CheckAdminToken 检查管理令牌：除非提供管理接口的监听都是 unix 套接字或回环地址，否则必须设置管理令牌
+func (s *ServerConfig) CheckAdminToken(listeners []Listener) error {
+	if !s.Admin.Enabled || s.Admin.Token != "" {
+		return nil
+	}
+	for _, l := range listeners {
+		if l.HasRoute(RouteAdmin) && !l.IsLocal() {
+			return fmt.Errorf("admin API enabled without token on non-local listener %s", l.Name)
+		}
+	}
+	return nil
+}
+
+// RedirectPort 根据配置检查 HTTP 重定向的目标端口，systemd 套接字的端口要到启动时才能确定，此时返回 0
+func (s *ServerConfig) RedirectPort() (int, error) {
+	return RedirectPortOf(s.ListenerConfigs(), nil)
+}
+
+// RedirectPortOf 返回 HTTP 重定向的目标端口：提供 api 路由的 TLS 监听的 TCP 端口。
+// addrs 为各监听实际打开的地址，不为空时以它为准（包括 systemd 传入的套接字）；
+// 为空时只根据配置计算，跳过 systemd 监听。没有这样的监听，或多个监听的端口不同时返回错误
+func RedirectPortOf(listeners []Listener, addrs []net.Addr) (int, error) {
+	port, deferred := 0, false
+	for i, l := range listeners {
+		if !l.TLS || !l.HasRoute(RouteAPI) {
+			continue
+		}
+		var p int
+		switch {
+		case addrs != nil:
+			addr, ok := addrs[i].(*net.TCPAddr)
+			if !ok {
+				return 0, fmt.Errorf("listener %s serves the api route over TLS on a %s socket, the redirect port is unknown", l.Name, addrs[i].Network())
+			}
+			p = addr.Port
+		case l.Network == NetworkSystemd:
+			deferred = true
+			continue
+		case l.Network != NetworkTCP:
+			return 0, fmt.Errorf("listener %s serves the api route over TLS on a %s socket, the redirect port is unknown", l.Name, l.Network)
+		default:
+			_, portText, err := net.SplitHostPort(l.Address)
+			if err != nil {
+				return 0, fmt.Errorf("listener %s: %v", l.Name, err)
+			}
+			p, err = strconv.Atoi(portText)
+			if err != nil || p <= 0 {
+				return 0, fmt.Errorf("listener %s has no fixed port", l.Name)
+			}
+		}
+		if port != 0 && p != port {
+			return 0, fmt.Errorf("TLS listeners serve the api route on ports %d and %d, the redirect port is ambiguous", port, p)
+		}
+		port = p
+	}
+	if port == 0 && !deferred {
+		return 0, fmt.Errorf("no TLS listener serves the api route")
+	}
+	return port, nil
+}
+
+// ClientCertProfile 客户端证书对应的服务端令牌配置，请求没有令牌时代替令牌使用
+type ClientCertProfile struct {
+	Name     string                 `json:"name"`      // 配置名称，用作令牌 ID，使用次数按名称统计
+	Match    []string               `json:"match"`     // 匹配的证书身份，如 cn:billing、dns:billing.internal、uri:spiffe://prod/billing、email:ops@example.com
+	KeyAlias string                 `json:"key_alias"` // admin.key_aliases 中的 API Key 别名
+	Provider string                 `json:"provider"`  // 为空时使用别名的提供商
+	MaxCalls int                    `json:"max_calls"` // 最大调用次数，0 表示不限制
+	ExtInfo  map[string]interface{} `json:"ext_info"`  // 与令牌 ext_info 相同的扩展信息
+}
+
+// clientCertIdentityKinds 客户端证书身份支持的前缀
+var clientCertIdentityKinds = []string{"cn", "dns", "uri", "email"}
+
+// DefaultACMECacheDir 默认的 ACME 证书缓存目录
+const DefaultACMECacheDir = "acme-cache"
+
+// DefaultBodyProcessThreshold 默认的 JSON 请求体缓冲阈值 (1MB)
+const DefaultBodyProcessThreshold = 1 << 20
+
+// DefaultShutdownTimeout 默认的关闭排空时间
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownTimeout 获取关闭时等待进行中请求的最长时间
+func (s *ServerConfig) ShutdownTimeout() time.Duration {
+	if s.Server.ShutdownTimeout > 0 {
+		return time.Duration(s.Server.ShutdownTimeout) * time.Second
+	}
+	return DefaultShutdownTimeout
+}
+
+// BodyProcessThreshold 获取 JSON 请求体缓冲阈值
+func (s *ServerConfig) BodyProcessThreshold() int64 {
+	if s.Proxy.Body.ProcessThreshold > 0 {
+		return s.Proxy.Body.ProcessThreshold
+	}
+	return DefaultBodyProcessThreshold
+}
+
+// MaxBodySize 获取指定 API 路径的请求体大小限制，按最长前缀匹配
+func (s *ServerConfig) MaxBodySize(path string) int64 {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "/"), "v1/")
+	limit := s.Proxy.Body.MaxSize
+	matched := -1
+	for prefix, size := range s.Proxy.Body.RouteMaxSize {
+		prefix = strings.TrimPrefix(strings.TrimPrefix(prefix, "/"), "v1/")
+		if strings.HasPrefix(path, prefix) && len(prefix) > matched {
+			limit = size
+			matched = len(prefix)
+		}
+	}
+	return limit
+}
+
+// CacheTTL 获取指定 API 路径的缓存秒数，按最长前缀匹配，0 表示不缓存
+func (s *ServerConfig) CacheTTL(path string) int {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "/"), "v1/")
+	ttl := s.Cache.TTL
+	matched := -1
+	for prefix, seconds := range s.Cache.RouteTTL {
+		prefix = strings.TrimPrefix(strings.TrimPrefix(prefix, "/"), "v1/")
+		if strings.HasPrefix(path, prefix) && len(prefix) > matched {
+			ttl = seconds
+			matched = len(prefix)
+		}
+	}
+	return ttl
 }
 
 // Config 完整配置结构
 type Config struct {
 	Server  ServerConfig
-	Clients map[string]ClientConfig // key 是配置的 SHA256 hash
+	Clients ClientRegistry // key 是配置的 SHA256 hash，可在运行时并发读写
 }
 
 // GenerateConfigHash 根据 crypto 参数生成配置的 hash
 func GenerateConfigHash(cfg *ClientConfig) string {
-	data := cfg.Crypto.Method + cfg.Crypto.AESKey + cfg.Crypto.AESIVSeed
-	hash := sha256.Sum256([]byte(data))
+	return hashCryptoParams(cfg.Crypto.Method, cfg.Crypto.AESKey, cfg.Crypto.AESIVSeed)
+}
+
+// hashCryptoParams 根据加密方法、密钥和 IV 种子计算 hash
+func hashCryptoParams(method, aesKey, ivSeed string) string {
+	hash := sha256.Sum256([]byte(method + aesKey + ivSeed))
 	return hex.EncodeToString(hash[:])
 }
 
+// APIURL 根据客户端配置生成带令牌的 API 地址，path 为附加的 API 路径，如 /chat/completions
+func (c *ClientConfig) APIURL(path, token string) string {
+	base := fmt.Sprintf("%s:%d%s", c.Server.Host, c.Server.Port, strings.TrimRight(c.Server.BasePath, "/"))
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	query := url.Values{}
+	query.Set("token", token)
+	query.Set("rai_hash", GenerateConfigHash(c))
+	return base + path + "?" + query.Encode()
+}
+
 // AddClientConfig 添加一个客户端配置
 func (c *Config) AddClientConfig(cfg ClientConfig) string {
-	hash := GenerateConfigHash(&cfg)
-	c.Clients[hash] = cfg
-	return hash
+	return c.Clients.Add(cfg)
 }
 
-// GetClientConfig 根据 hash 获取客户端配置
+// GetClientConfig 根据 hash 获取客户端配置，也接受未退役的旧密钥的 hash
 func (c *Config) GetClientConfig(hash string) (ClientConfig, bool) {
-	cfg, ok := c.Clients[hash]
-	return cfg, ok
+	return c.Clients.Get(hash)
+}
+
+// RemoveClientConfig 删除客户端配置，返回配置是否存在
+func (c *Config) RemoveClientConfig(hash string) bool {
+	return c.Clients.Remove(hash)
+}
+
+// ClientConfigs 返回所有客户端配置的副本
+func (c *Config) ClientConfigs() map[string]ClientConfig {
+	return c.Clients.All()
 }
 
 // DefaultClientConfig 创建默认的客户端配置
@@ -100,55 +468,114 @@ func DefaultClientConfig(host string, port int) (ClientConfig, error) {
 		port = 8840
 	}
 
+	aesKey, ivSeed, err := generateKey()
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	var cfg ClientConfig
+	cfg.Version = "1.0.0"
+	cfg.Server.Host = host
+	cfg.Server.Port = port
+	cfg.Server.BasePath = "/relayapi/"
+	cfg.Crypto.Method = "aes"
+	cfg.Crypto.AESKey = aesKey
+	cfg.Crypto.AESIVSeed = ivSeed
+	return cfg, nil
+}
+
+// RotateClientConfig 生成下一代密钥，当前密钥和未退役的旧密钥保留到 retireAt
+func RotateClientConfig(cfg ClientConfig, retireAt time.Time) (ClientConfig, error) {
+	aesKey, ivSeed, err := generateKey()
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	now := time.Now()
+	previous := []PreviousKey{{
+		AESKey:     cfg.Crypto.AESKey,
+		AESIVSeed:  cfg.Crypto.AESIVSeed,
+		Generation: cfg.Crypto.Generation,
+		RetireAt:   retireAt,
+	}}
+	for _, key := range cfg.Crypto.PreviousKeys {
+		// 已退役的密钥不再保留，仍有效的旧密钥保持原来的退役时间
+		if key.Active(now) {
+			previous = append(previous, key)
+		}
+	}
+
+	rotated := cfg
+	rotated.Crypto.AESKey = aesKey
+	rotated.Crypto.AESIVSeed = ivSeed
+	rotated.Crypto.Generation = cfg.Crypto.Generation + 1
+	rotated.Crypto.PreviousKeys = previous
+	return rotated, nil
+}
+
+// RewriteClientCrypto 返回把 .rai 文件中的 crypto 字段替换为 cfg.Crypto 后的文件内容，
+// 其他字段（包括其中的 ${VAR} 和 *_file 引用）保持原样。只支持 JSON 文件，
+// crypto 字段本身使用 ${VAR} 或 *_file 时返回错误，避免把解析后的密钥写回文件
+func RewriteClientCrypto(filePath string, cfg *ClientConfig) ([]byte, error) {
+	if format := FormatOf(filePath); format != FormatJSON {
+		return nil, fmt.Errorf("cannot rewrite %s client config files", format)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client config file: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse client config file: %v", err)
+	}
+
+	var crypto map[string]json.RawMessage
+	if err := json.Unmarshal(fields["crypto"], &crypto); err != nil {
+		return nil, fmt.Errorf("failed to parse crypto: %v", err)
+	}
+	for key, value := range crypto {
+		if strings.HasSuffix(key, fileSuffix) || envPattern.Match(value) {
+			return nil, fmt.Errorf("crypto.%s is read from the environment or a file, update it there instead", key)
+		}
+	}
+
+	if fields["crypto"], err = json.Marshal(cfg.Crypto); err != nil {
+		return nil, err
+	}
+	rewritten, err := json.MarshalIndent(fields, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(rewritten, '\n'), nil
+}
+
+// generateKey 生成随机的 AES 密钥和 IV 种子
+func generateKey() (aesKey, ivSeed string, err error) {
 	// 生成随机的 AES 密钥 (32字节/256位)
-	aesKey := make([]byte, 32)
-	if _, err := rand.Read(aesKey); err != nil {
-		return ClientConfig{}, fmt.Errorf("failed to generate random AES key: %v", err)
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", "", fmt.Errorf("failed to generate random AES key: %v", err)
 	}
 
 	// 生成随机的 IV seed (8字节/64位)
-	ivSeed := make([]byte, 8)
-	if _, err := rand.Read(ivSeed); err != nil {
-		return ClientConfig{}, fmt.Errorf("failed to generate random IV seed: %v", err)
-	}
-
-	return ClientConfig{
-		Version: "1.0.0",
-		Server: struct {
-			Host     string `json:"host"`
-			Port     int    `json:"port"`
-			BasePath string `json:"base_path"`
-		}{
-			Host:     host,
-			Port:     port,
-			BasePath: "/relayapi/",
-		},
-		Crypto: struct {
-			Method    string `json:"method"`
-			AESKey    string `json:"aes_key"`
-			AESIVSeed string `json:"aes_iv_seed"`
-		}{
-			Method:    "aes",
-			AESKey:    hex.EncodeToString(aesKey),
-			AESIVSeed: hex.EncodeToString(ivSeed),
-		},
-	}, nil
+	seed := make([]byte, 8)
+	if _, err := rand.Read(seed); err != nil {
+		return "", "", fmt.Errorf("failed to generate random IV seed: %v", err)
+	}
+
+	return hex.EncodeToString(key), hex.EncodeToString(seed), nil
 }
 
 // LoadConfig 加载配置
 func LoadConfig(serverConfigPath string, clientConfigPath string) (*Config, error) {
-	config := &Config{
-		Clients: make(map[string]ClientConfig),
-	}
+	config := &Config{}
 
 	// 加载服务器配置
-	serverData, err := os.ReadFile(serverConfigPath)
+	server, err := LoadServerConfig(serverConfigPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read server config: %v", err)
-	}
-	if err := json.Unmarshal(serverData, &config.Server); err != nil {
-		return nil, fmt.Errorf("failed to parse server config: %v", err)
+		return nil, err
 	}
+	config.Server = *server
 
 	// 检查是否是目录
 	fileInfo, err := os.Stat(clientConfigPath)
@@ -167,53 +594,110 @@ func LoadConfig(serverConfigPath string, clientConfigPath string) (*Config, erro
 			if !file.IsDir() && strings.HasSuffix(file.Name(), ".rai") {
 				filePath := filepath.Join(clientConfigPath, file.Name())
 				if err := loadClientConfigFile(filePath, config); err != nil {
-					log.Printf("Warning: failed to load client config %s: %v", filePath, err)
+					slog.Warn("Failed to load client config", "path", filePath, "error", err)
 					continue
 				}
-				log.Println("load client config:", filePath)
+				slog.Info("Loaded client config", "path", filePath)
 			}
 		}
 
 		// 启动文件监控
-		go watchConfigDirectory(clientConfigPath, config)
+		go watchConfigDirectory(clientConfigPath, "", config)
 	} else {
 		// 加载单个配置文件
 		if err := loadClientConfigFile(clientConfigPath, config); err != nil {
 			return nil, fmt.Errorf("failed to load client config: %v", err)
 		}
+
+		// 监控文件所在目录，只处理该文件（编辑器通常以重命名方式保存文件）
+		go watchConfigDirectory(filepath.Dir(clientConfigPath), filepath.Base(clientConfigPath), config)
 	}
 
 	return config, nil
 }
 
+// LoadServerConfig 读取并解析服务器配置文件，替换 ${VAR} 和 *_file 后再应用 RELAYAPI_* 环境变量
+func LoadServerConfig(path string) (*ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server config: %v", err)
+	}
+	server := &ServerConfig{}
+	if err := decodeConfigFile(path, data, serverSchema, server); err != nil {
+		return nil, fmt.Errorf("failed to parse server config: %v", err)
+	}
+	if err := applyEnvOverrides(server); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
 // loadClientConfigFile 加载单个客户端配置文件
 func loadClientConfigFile(filePath string, config *Config) error {
+	clientConfig, err := ReadClientConfigFile(filePath)
+	if err != nil {
+		return err
+	}
+	config.Clients.SetFile(filePath, clientConfig)
+	return nil
+}
+
+// ReadClientConfigFile 读取并解析客户端配置文件，与服务器加载 .rai 文件的方式相同：
+// 支持 JSON、YAML 和 TOML，替换 ${VAR} 和 *_file 字段并按 Schema 校验
+func ReadClientConfigFile(filePath string) (ClientConfig, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read client config file: %v", err)
+		return ClientConfig{}, fmt.Errorf("failed to read client config file: %v", err)
 	}
 
 	var clientConfig ClientConfig
-	if err := json.Unmarshal(data, &clientConfig); err != nil {
-		log.Println("ERROR: clientConfig  unmarshal error ,filePath: ", filePath)
-		return fmt.Errorf("failed to parse client config file: %v", err)
+	if err := decodeConfigFile(filePath, data, clientSchema, &clientConfig); err != nil {
+		return ClientConfig{}, fmt.Errorf("failed to parse client config file: %v", err)
+	}
+	return clientConfig, nil
+}
+
+// reloadClientConfigFile 重新加载被创建或修改的客户端配置文件，无效的配置不会替换已加载的配置
+func reloadClientConfigFile(filePath string, config *Config) error {
+	clientConfig, err := ReadClientConfigFile(filePath)
+	if err != nil {
+		return err
+	}
+	if err := ValidateClientConfig(&clientConfig); err != nil {
+		return err
 	}
-	log.Println("clientConfig added :", clientConfig)
-	config.AddClientConfig(clientConfig)
+	config.Clients.SetFile(filePath, clientConfig)
 	return nil
 }
 
-// watchConfigDirectory 监控配置目录的变化
-func watchConfigDirectory(dirPath string, config *Config) {
+// handleClientConfigEvent 根据文件事件更新客户端配置注册表
+func handleClientConfigEvent(event fsnotify.Event, config *Config) {
+	switch {
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		// 删除或重命名（移走）的文件不再有效，重命名后的新文件会收到 Create 事件
+		if config.Clients.RemoveFile(event.Name) {
+			slog.Info("Removed client config", "path", event.Name)
+		}
+	case event.Op&(fsnotify.Create|fsnotify.Write) != 0:
+		if err := reloadClientConfigFile(event.Name, config); err != nil {
+			slog.Error("Failed to load client config, keeping the current config", "path", event.Name, "error", err)
+		} else {
+			slog.Info("Loaded client config", "path", event.Name)
+		}
+	}
+}
+
+// watchConfigDirectory 监控配置目录的变化，only 不为空时只处理该文件名
+func watchConfigDirectory(dirPath, only string, config *Config) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Printf("Failed to create file watcher: %v", err)
+		slog.Warn("Failed to create client config watcher", "error", err)
 		return
 	}
 	defer watcher.Close()
 
 	if err := watcher.Add(dirPath); err != nil {
-		log.Printf("Failed to watch directory: %v", err)
+		slog.Warn("Failed to watch client config directory", "path", dirPath, "error", err)
 		return
 	}
 
@@ -223,20 +707,17 @@ func watchConfigDirectory(dirPath string, config *Config) {
 			if !ok {
 				return
 			}
-			if event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Write == fsnotify.Write {
-				if strings.HasSuffix(event.Name, ".rai") {
-					if err := loadClientConfigFile(event.Name, config); err != nil {
-						log.Printf("Failed to load new client config %s: %v", event.Name, err)
-					} else {
-						log.Printf("Loaded new client config: %s", event.Name)
-					}
-				}
+			if only != "" && filepath.Base(event.Name) != only {
+				continue
+			}
+			if strings.HasSuffix(event.Name, ".rai") || only != "" {
+				handleClientConfigEvent(event, config)
 			}
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
 			}
-			log.Printf("Watcher error: %v", err)
+			slog.Warn("Client config watcher error", "error", err)
 		}
 	}
 }
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientRegistry 并发安全的客户端配置注册表。
+// 读取方无锁地读取不可变快照，写入方复制快照修改后原子替换（copy-on-write）。
+type ClientRegistry struct {
+	mu       sync.Mutex // 串行化写入
+	snapshot atomic.Pointer[clientSnapshot]
+}
+
+// clientSnapshot 注册表的不可变快照，创建后不再修改
+type clientSnapshot struct {
+	version uint64
+	clients map[string]ClientConfig // 配置 hash -> 客户端配置
+	files   map[string]string       // .rai 文件路径 -> 配置 hash
+	aliases map[string]string       // 旧密钥 hash -> 当前配置 hash
+}
+
+var emptySnapshot = &clientSnapshot{}
+
+// load 返回当前快照，零值注册表返回空快照
+func (r *ClientRegistry) load() *clientSnapshot {
+	if s := r.snapshot.Load(); s != nil {
+		return s
+	}
+	return emptySnapshot
+}
+
+// update 在写锁内复制当前快照，交给 mutate 修改后发布新快照
+func (r *ClientRegistry) update(mutate func(clients map[string]ClientConfig, files map[string]string) bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.load()
+	clients := make(map[string]ClientConfig, len(current.clients)+1)
+	for hash, cfg := range current.clients {
+		clients[hash] = cfg
+	}
+	files := make(map[string]string, len(current.files)+1)
+	for path, hash := range current.files {
+		files[path] = hash
+	}
+	if !mutate(clients, files) {
+		return false
+	}
+
+	aliases := make(map[string]string)
+	for hash, cfg := range clients {
+		for _, key := range cfg.Crypto.PreviousKeys {
+			aliases[key.Hash(cfg.Crypto.Method)] = hash
+		}
+	}
+	r.snapshot.Store(&clientSnapshot{
+		version: current.version + 1,
+		clients: clients,
+		files:   files,
+		aliases: aliases,
+	})
+	return true
+}
+
+// Version 返回注册表的版本号，每次修改加一，用于让依赖配置的缓存失效
+func (r *ClientRegistry) Version() uint64 {
+	return r.load().version
+}
+
+// Len 返回客户端配置数量
+func (r *ClientRegistry) Len() int {
+	return len(r.load().clients)
+}
+
+// Get 根据 hash 获取客户端配置，也接受未退役的旧密钥的 hash
+func (r *ClientRegistry) Get(hash string) (ClientConfig, bool) {
+	cfg, _, ok := r.Lookup(hash)
+	return cfg, ok
+}
+
+// Lookup 与 Get 相同，同时返回读取时快照的版本号，保证配置和版本号一致
+func (r *ClientRegistry) Lookup(hash string) (ClientConfig, uint64, bool) {
+	s := r.load()
+	if cfg, ok := s.clients[hash]; ok {
+		return cfg, s.version, true
+	}
+	current, ok := s.aliases[hash]
+	if !ok {
+		return ClientConfig{}, s.version, false
+	}
+	cfg := s.clients[current]
+	now := time.Now()
+	for _, key := range cfg.Crypto.PreviousKeys {
+		if key.Active(now) && key.Hash(cfg.Crypto.Method) == hash {
+			return cfg, s.version, true
+		}
+	}
+	return ClientConfig{}, s.version, false
+}
+
+// All 返回所有客户端配置的副本
+func (r *ClientRegistry) All() map[string]ClientConfig {
+	s := r.load()
+	clients := make(map[string]ClientConfig, len(s.clients))
+	for hash, cfg := range s.clients {
+		clients[hash] = cfg
+	}
+	return clients
+}
+
+// Add 添加一个不对应文件的客户端配置（如通过管理接口添加）
+func (r *ClientRegistry) Add(cfg ClientConfig) string {
+	hash := GenerateConfigHash(&cfg)
+	r.update(func(clients map[string]ClientConfig, files map[string]string) bool {
+		clients[hash] = cfg
+		return true
+	})
+	return hash
+}
+
+// Remove 删除客户端配置及其对应的文件记录，返回配置是否存在
+func (r *ClientRegistry) Remove(hash string) bool {
+	return r.update(func(clients map[string]ClientConfig, files map[string]string) bool {
+		if _, ok := clients[hash]; !ok {
+			return false
+		}
+		delete(clients, hash)
+		for path, fileHash := range files {
+			if fileHash == hash {
+				delete(files, path)
+			}
+		}
+		return true
+	})
+}
+
+// SetFile 添加或替换 .rai 文件对应的客户端配置。文件内容变化（如密钥轮换）时，
+// 旧的配置在没有其他文件引用时被移除
+func (r *ClientRegistry) SetFile(path string, cfg ClientConfig) string {
+	path = filepath.Clean(path)
+	hash := GenerateConfigHash(&cfg)
+	r.update(func(clients map[string]ClientConfig, files map[string]string) bool {
+		if previous, ok := files[path]; ok && previous != hash {
+			delete(files, path)
+			if !referenced(files, previous) {
+				delete(clients, previous)
+			}
+		}
+		files[path] = hash
+		clients[hash] = cfg
+		return true
+	})
+	return hash
+}
+
+// RemoveFile 移除已删除或重命名的 .rai 文件对应的客户端配置，返回是否有配置被移除
+func (r *ClientRegistry) RemoveFile(path string) bool {
+	path = filepath.Clean(path)
+	return r.update(func(clients map[string]ClientConfig, files map[string]string) bool {
+		hash, ok := files[path]
+		if !ok {
+			return false
+		}
+		delete(files, path)
+		if !referenced(files, hash) {
+			delete(clients, hash)
+		}
+		return true
+	})
+}
+
+// referenced 判断是否还有其他文件使用该配置
+func referenced(files map[string]string, hash string) bool {
+	for _, fileHash := range files {
+		if fileHash == hash {
+			return true
+		}
+	}
+	return false
+}
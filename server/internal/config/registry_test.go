@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func newClient(t *testing.T) ClientConfig {
+	t.Helper()
+	cfg, err := DefaultClientConfig("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func writeClient(t *testing.T, path string, cfg ClientConfig) {
+	t.Helper()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryFiles(t *testing.T) {
+	var registry ClientRegistry
+	first, second := newClient(t), newClient(t)
+
+	hash := registry.SetFile("a.rai", first)
+	if _, ok := registry.Get(hash); !ok {
+		t.Fatal("Expected client from file to be registered")
+	}
+
+	// 修改文件内容后旧配置被替换
+	version := registry.Version()
+	newHash := registry.SetFile("./a.rai", second)
+	if _, ok := registry.Get(hash); ok {
+		t.Error("Expected replaced client to be removed")
+	}
+	if _, ok := registry.Get(newHash); !ok {
+		t.Error("Expected modified client to be registered")
+	}
+	if registry.Version() <= version {
+		t.Error("Expected version to increase")
+	}
+
+	// 两个文件内容相同时，删除其中一个不影响另一个
+	registry.SetFile("b.rai", second)
+	registry.RemoveFile("a.rai")
+	if _, ok := registry.Get(newHash); !ok {
+		t.Error("Expected client still referenced by b.rai to stay")
+	}
+	registry.RemoveFile("b.rai")
+	if registry.Len() != 0 {
+		t.Errorf("Expected empty registry, got %d clients", registry.Len())
+	}
+	if registry.RemoveFile("missing.rai") {
+		t.Error("Expected removing unknown file to report false")
+	}
+}
+
+func TestRegistryPreviousKeyAlias(t *testing.T) {
+	var registry ClientRegistry
+	original := newClient(t)
+	oldHash := GenerateConfigHash(&original)
+
+	rotated, err := RotateClientConfig(original, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newHash := registry.Add(rotated)
+
+	cfg, ok := registry.Get(oldHash)
+	if !ok || GenerateConfigHash(&cfg) != newHash {
+		t.Fatal("Expected previous key hash to resolve to the rotated client")
+	}
+
+	expired, err := RotateClientConfig(original, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Remove(newHash)
+	registry.Add(expired)
+	if _, ok := registry.Get(oldHash); ok {
+		t.Error("Expected retired key hash to be rejected")
+	}
+}
+
+func TestRewriteClientCrypto(t *testing.T) {
+	dir := t.TempDir()
+	original := newClient(t)
+	t.Setenv("RELAYAPI_TEST_HOST", "relay.example.com")
+
+	// 其他字段中的 ${VAR} 引用按服务器的方式读取，改写后保持原样
+	path := filepath.Join(dir, "default.rai")
+	data := `{"version": "1.0.0", "server": {"host": "${RELAYAPI_TEST_HOST}", "port": 8840},
+		"crypto": {"method": "aes", "aes_key": "` + original.Crypto.AESKey + `", "aes_iv_seed": "` + original.Crypto.AESIVSeed + `"}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadClientConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Server.Host != "relay.example.com" || loaded.Server.Port != 8840 {
+		t.Fatalf("Expected references to be resolved, got %+v", loaded.Server)
+	}
+
+	rotated, err := RotateClientConfig(loaded, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewritten, err := RewriteClientCrypto(path, &rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(rewritten), "${RELAYAPI_TEST_HOST}") || strings.Contains(string(rewritten), "relay.example.com") {
+		t.Errorf("Expected references outside crypto to be kept, got %s", rewritten)
+	}
+	if err := os.WriteFile(path, rewritten, 0600); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := ReadClientConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GenerateConfigHash(&reloaded) != GenerateConfigHash(&rotated) {
+		t.Error("Expected the rewritten file to load as the rotated config")
+	}
+
+	// crypto 本身使用引用或文件不是 JSON 时拒绝改写
+	for name, data := range map[string]string{
+		"env.rai":  `{"crypto": {"method": "aes", "aes_key": "${RELAYAPI_TEST_KEY}"}}`,
+		"file.rai": `{"crypto": {"method": "aes", "aes_key_file": "/run/secrets/key"}}`,
+		"c.yaml":   "crypto:\n  method: aes\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RewriteClientCrypto(path, &rotated); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	var registry ClientRegistry
+	clients := make([]ClientConfig, 8)
+	for i := range clients {
+		clients[i] = newClient(t)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for hash, cfg := range registry.All() {
+					if got, ok := registry.Get(hash); ok && got.Crypto.AESKey != cfg.Crypto.AESKey {
+						t.Error("Inconsistent client config")
+					}
+				}
+				registry.Version()
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		path := filepath.Join("dir", string(rune('a'+i%4))+".rai")
+		hash := registry.SetFile(path, clients[i%len(clients)])
+		if i%3 == 0 {
+			registry.RemoveFile(path)
+		}
+		if i%5 == 0 {
+			registry.Remove(hash)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestClientConfigEvents(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{}
+	path := filepath.Join(dir, "client.rai")
+
+	first := newClient(t)
+	writeClient(t, path, first)
+	handleClientConfigEvent(fsnotify.Event{Name: path, Op: fsnotify.Create}, cfg)
+	if _, ok := cfg.GetClientConfig(GenerateConfigHash(&first)); !ok {
+		t.Fatal("Expected created file to be loaded")
+	}
+
+	// 无效的修改不会替换已加载的配置
+	if err := os.WriteFile(path, []byte(`{"crypto":{"method":"aes"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	handleClientConfigEvent(fsnotify.Event{Name: path, Op: fsnotify.Write}, cfg)
+	if _, ok := cfg.GetClientConfig(GenerateConfigHash(&first)); !ok {
+		t.Fatal("Expected invalid modification to keep the previous config")
+	}
+
+	second := newClient(t)
+	writeClient(t, path, second)
+	handleClientConfigEvent(fsnotify.Event{Name: path, Op: fsnotify.Write}, cfg)
+	if _, ok := cfg.GetClientConfig(GenerateConfigHash(&first)); ok {
+		t.Error("Expected modified file to replace the old config")
+	}
+
+	// 重命名：旧文件名收到 Rename，新文件名收到 Create
+	renamed := filepath.Join(dir, "renamed.rai")
+	if err := os.Rename(path, renamed); err != nil {
+		t.Fatal(err)
+	}
+	handleClientConfigEvent(fsnotify.Event{Name: path, Op: fsnotify.Rename}, cfg)
+	handleClientConfigEvent(fsnotify.Event{Name: renamed, Op: fsnotify.Create}, cfg)
+	if _, ok := cfg.GetClientConfig(GenerateConfigHash(&second)); !ok {
+		t.Error("Expected renamed file to stay loaded")
+	}
+
+	if err := os.Remove(renamed); err != nil {
+		t.Fatal(err)
+	}
+	handleClientConfigEvent(fsnotify.Event{Name: renamed, Op: fsnotify.Remove}, cfg)
+	if cfg.Clients.Len() != 0 {
+		t.Errorf("Expected deleted file to be unloaded, %d clients left", cfg.Clients.Len())
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay 合并编辑器保存文件时产生的连续事件
+const reloadDelay = 200 * time.Millisecond
+
+// ApplyFunc 应用重新加载的服务器配置，返回错误时不能修改任何运行时状态
+type ApplyFunc func(server *ServerConfig) error
+
+// ReloadServerConfig 重新读取并验证服务器配置，通过后交给 apply 应用。
+// 配置无效或 apply 失败时保留当前配置并返回错误
+func ReloadServerConfig(path string, apply ApplyFunc) error {
+	server, err := LoadServerConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := ValidateServerConfig(server); err != nil {
+		return err
+	}
+	return apply(server)
+}
+
+// WatchServerConfig 在 hup 收到信号或配置文件变化时重新加载服务器配置，stop 关闭后返回。
+// hup 由调用方在启动时通过 signal.Notify 注册 SIGHUP 并保持到进程退出，
+// 否则监控启动前或关闭期间收到的 SIGHUP 会按默认行为结束进程
+func WatchServerConfig(path string, apply ApplyFunc, hup <-chan os.Signal, stop <-chan struct{}) {
+	// 监控文件所在目录，编辑器通常以重命名方式保存文件
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Failed to create server config watcher, reload on SIGHUP only", "error", err)
+	} else {
+		defer watcher.Close()
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			slog.Warn("Failed to watch server config, reload on SIGHUP only", "path", path, "error", err)
+		} else {
+			events, errs = watcher.Events, watcher.Errors
+		}
+	}
+
+	reload := func(reason string) {
+		if err := ReloadServerConfig(path, apply); err != nil {
+			slog.Error("Failed to reload server config, keeping the current config", "path", path, "reason", reason, "error", err)
+			return
+		}
+		slog.Info("Reloaded server config", "path", path, "reason", reason)
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	name := filepath.Base(path)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-hup:
+			reload("SIGHUP")
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if filepath.Base(event.Name) == name && event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				timer.Reset(reloadDelay)
+			}
+		case <-timer.C:
+			reload("file changed")
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			slog.Warn("Server config watcher error", "error", err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func writeServerConfig(t *testing.T, path string, rps int) {
+	t.Helper()
+	server := &ServerConfig{}
+	server.Server.Port = 8840
+	server.Server.ReadTimeout = 30
+	server.Server.WriteTimeout = 30
+	server.RateLimit.RequestsPerSecond = rps
+	server.RateLimit.Burst = 10
+	data, err := json.Marshal(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadServerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	var applied *ServerConfig
+	apply := func(server *ServerConfig) error {
+		applied = server
+		return nil
+	}
+
+	writeServerConfig(t, path, 50)
+	if err := ReloadServerConfig(path, apply); err != nil {
+		t.Fatal(err)
+	}
+	if applied == nil || applied.RateLimit.RequestsPerSecond != 50 {
+		t.Fatal("Expected valid config to be applied")
+	}
+
+	// 无效的配置不会交给 apply
+	applied = nil
+	writeServerConfig(t, path, 0)
+	if err := ReloadServerConfig(path, apply); err == nil {
+		t.Error("Expected invalid config to be rejected")
+	}
+	if err := os.WriteFile(path, []byte(`{"server":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReloadServerConfig(path, apply); err == nil {
+		t.Error("Expected malformed config to be rejected")
+	}
+	if applied != nil {
+		t.Error("Expected rejected config not to be applied")
+	}
+
+	// apply 失败的错误返回给调用方
+	writeServerConfig(t, path, 50)
+	failed := errors.New("failed to create log writer")
+	if err := ReloadServerConfig(path, func(*ServerConfig) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Expected apply error, got %v", err)
+	}
+}
+
+func TestWatchServerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeServerConfig(t, path, 50)
+
+	applied := make(chan int, 10)
+	hup := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go WatchServerConfig(path, func(server *ServerConfig) error {
+		applied <- server.RateLimit.RequestsPerSecond
+		return nil
+	}, hup, stop)
+
+	// 等待监控启动后修改文件
+	time.Sleep(100 * time.Millisecond)
+	writeServerConfig(t, path, 80)
+
+	select {
+	case rps := <-applied:
+		if rps != 80 {
+			t.Errorf("Expected reloaded rate 80, got %d", rps)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for config reload")
+	}
+
+	// 调用方注册的 SIGHUP 同样触发重新加载
+	hup <- syscall.SIGHUP
+	select {
+	case rps := <-applied:
+		if rps != 80 {
+			t.Errorf("Expected reloaded rate 80, got %d", rps)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for SIGHUP reload")
+	}
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schemaDraft 生成的 JSON Schema 使用的规范版本
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// secretFilePattern 任意字段都可以加 _file 后缀从文件读取值
+const secretFilePattern = "_file$"
+
+// Schema 由配置结构体生成的 JSON Schema（只使用校验配置所需的子集）
+type Schema struct {
+	Draft                string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false 或 *Schema
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+var (
+	serverSchema = newSchema("RelayAPI server configuration", reflect.TypeOf(ServerConfig{}))
+	clientSchema = newSchema("RelayAPI client configuration (.rai)", reflect.TypeOf(ClientConfig{}))
+)
+
+// ServerSchema 返回 config.json 的 JSON Schema
+func ServerSchema() *Schema {
+	return serverSchema
+}
+
+// ClientSchema 返回 .rai 文件的 JSON Schema
+func ClientSchema() *Schema {
+	return clientSchema
+}
+
+// newSchema 生成带标题的根 Schema
+func newSchema(title string, t reflect.Type) *Schema {
+	s := schemaFor(t)
+	s.Draft = schemaDraft
+	s.Title = title
+	return s
+}
+
+// schemaFor 根据 Go 类型和 json 标签生成 Schema
+func schemaFor(t reflect.Type) *Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.Struct:
+		s := &Schema{
+			Type:                 "object",
+			Properties:           make(map[string]*Schema),
+			PatternProperties:    map[string]*Schema{secretFilePattern: {Type: "string"}},
+			AdditionalProperties: false,
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("json"), ",")[0]
+			if tag == "" || tag == "-" || !field.IsExported() {
+				continue
+			}
+			s.Properties[tag] = schemaFor(field.Type)
+		}
+		return s
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaFor(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaFor(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	return &Schema{}
+}
+
+// Problem 配置中的一个问题及其位置
+type Problem struct {
+	File    string // 配置文件，为空时省略
+	Path    string // 字段路径，如 log.database.connection_string
+	Message string
+}
+
+func (p Problem) Error() string {
+	var prefix string
+	if p.File != "" {
+		prefix = p.File + ": "
+	}
+	if p.Path != "" {
+		prefix += p.Path + ": "
+	}
+	return prefix + p.Message
+}
+
+// Problems 配置检查发现的全部问题
+type Problems []Problem
+
+func (p Problems) Error() string {
+	messages := make([]string, len(p))
+	for i, problem := range p {
+		messages[i] = problem.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Err 没有问题时返回 nil
+func (p Problems) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return p
+}
+
+// add 记录一个问题
+func (p *Problems) add(path, format string, args ...interface{}) {
+	*p = append(*p, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// inFile 为所有问题设置所在的文件
+func (p Problems) inFile(file string) Problems {
+	for i := range p {
+		p[i].File = file
+	}
+	return p
+}
+
+// Validate 检查由 JSON、YAML 或 TOML 解析出的值，返回所有不符合 Schema 的字段
+func (s *Schema) Validate(value interface{}) Problems {
+	var problems Problems
+	s.validate(value, "", &problems)
+	return problems
+}
+
+func (s *Schema) validate(value interface{}, path string, problems *Problems) {
+	// null 表示使用零值
+	if value == nil {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			problems.add(path, "expected object, got %s", jsonType(value))
+			return
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s.validateProperty(key, object[key], joinPath(path, key), problems)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			problems.add(path, "expected array, got %s", jsonType(value))
+			return
+		}
+		for i, item := range items {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			problems.add(path, "expected string, got %s", jsonType(value))
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				problems.add(path, "expected RFC 3339 date-time, got %q", str)
+			}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems.add(path, "expected boolean, got %s", jsonType(value))
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			problems.add(path, "expected integer, got %s", jsonType(value))
+			return
+		}
+		if _, err := number.Int64(); err != nil {
+			problems.add(path, "expected integer, got %s", number)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			problems.add(path, "expected number, got %s", jsonType(value))
+		}
+	}
+}
+
+// validateProperty 按 properties、patternProperties、additionalProperties 的顺序匹配对象的字段
+func (s *Schema) validateProperty(key string, value interface{}, path string, problems *Problems) {
+	if property, ok := s.Properties[key]; ok {
+		property.validate(value, path, problems)
+		return
+	}
+	for pattern, property := range s.PatternProperties {
+		if regexp.MustCompile(pattern).MatchString(key) {
+			property.validate(value, path, problems)
+			return
+		}
+	}
+	switch additional := s.AdditionalProperties.(type) {
+	case *Schema:
+		additional.validate(value, path, problems)
+	case bool:
+		if !additional {
+			problems.add(path, "unknown field")
+		}
+	}
+}
+
+// hasProperty 判断对象是否声明了该字段
+func (s *Schema) hasProperty(key string) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.Properties[key]
+	return ok
+}
+
+// property 返回对象字段或 map 值的 Schema，未知时返回 nil
+func (s *Schema) property(key string) *Schema {
+	if s == nil {
+		return nil
+	}
+	if property, ok := s.Properties[key]; ok {
+		return property
+	}
+	if additional, ok := s.AdditionalProperties.(*Schema); ok {
+		return additional
+	}
+	return nil
+}
+
+// joinPath 拼接字段路径
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonType 返回值在 JSON 中的类型名
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadServerConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+server:
+  port: 8840
+  read_timeout: 30
+  write_timeout: 30
+rate_limit:
+  requests_per_second: 20
+  burst: 40
+proxy:
+  body:
+    route_max_size:
+      /audio: 1073741824
+`,
+		"config.toml": `
+[server]
+port = 8840
+read_timeout = 30
+write_timeout = 30
+
+[rate_limit]
+requests_per_second = 20
+burst = 40
+
+[proxy.body.route_max_size]
+"/audio" = 1073741824
+`,
+	}
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			server, err := LoadServerConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateServerConfig(server); err != nil {
+				t.Fatal(err)
+			}
+			if server.RateLimit.Burst != 40 || server.Proxy.Body.RouteMaxSize["/audio"] != 1<<30 {
+				t.Errorf("Unexpected config: %+v", server)
+			}
+		})
+	}
+}
+
+func TestLoadServerConfigUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"server": {"port": 8840, "read_timout": 30}, "rate_limit": {"burst": "40"}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadServerConfig(path)
+	if err == nil {
+		t.Fatal("Expected unknown field to be rejected")
+	}
+	for _, want := range []string{"server.read_timout: unknown field", "rate_limit.burst: expected integer"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+func TestCheckConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	serverPath := filepath.Join(dir, "config.yaml")
+	data := `
+server:
+  port: 8840
+  read_timeout: 0
+  write_timeout: 30
+  debg: true
+log:
+  web:
+    enabled: true
+rate_limit:
+  requests_per_second: 20
+  burst: 40
+`
+	if err := os.WriteFile(serverPath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	clients := filepath.Join(dir, "clients")
+	if err := os.Mkdir(clients, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeClient(t, filepath.Join(clients, "good.rai"), newClient(t))
+	bad := `{"server": {"port": 8840}, "crypto": {"method": "aes", "aes_key": "short", "aes_iv_seed": 1}}`
+	if err := os.WriteFile(filepath.Join(clients, "bad.rai"), []byte(bad), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, problem := range CheckConfigFiles(serverPath, clients) {
+		got = append(got, filepath.Base(problem.File)+": "+problem.Path)
+	}
+	want := []string{
+		"config.yaml: server.debg",
+		"config.yaml: server.read_timeout",
+		"config.yaml: log.web.callback_url",
+		"bad.rai: crypto.aes_iv_seed",
+		"bad.rai: crypto.aes_key",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestPublishedSchemas 确保 docs/schema 中发布的 Schema 与配置结构体一致
+func TestPublishedSchemas(t *testing.T) {
+	for name, schema := range map[string]*Schema{"server": ServerSchema(), "client": ClientSchema()} {
+		path := filepath.Join("..", "..", "..", "docs", "schema", name+".schema.json")
+		published, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(published)) != string(generated) {
+			t.Errorf("%s is out of date, regenerate it with: relayapi-server --schema %s > %s", path, name, path)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"fmt"
 )
 
@@ -30,15 +31,25 @@ func (e *AESEncryptor) Encrypt(data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to create AES cipher: %v", err)
 	}
 
+	// 生成随机 IV 并与 IV 种子混合（与各语言 SDK 一致）
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate IV: %v", err)
+	}
+	for i := range iv {
+		iv[i] ^= e.ivSeed[i]
+	}
+
 	// 使用 CBC 模式
-	mode := cipher.NewCBCEncrypter(block, e.ivSeed)
+	mode := cipher.NewCBCEncrypter(block, iv)
 
 	// 填充数据
 	paddedData := pkcs7Padding(data, aes.BlockSize)
 
-	// 加密数据
-	ciphertext := make([]byte, len(paddedData))
-	mode.CryptBlocks(ciphertext, paddedData)
+	// 加密数据，IV 放在密文前面，与 Decrypt 对应
+	ciphertext := make([]byte, aes.BlockSize+len(paddedData))
+	copy(ciphertext, iv)
+	mode.CryptBlocks(ciphertext[aes.BlockSize:], paddedData)
 
 	return ciphertext, nil
 }
@@ -48,6 +59,9 @@ func (e *AESEncryptor) Decrypt(data []byte) ([]byte, error) {
 	if len(data) < aes.BlockSize {
 		return nil, fmt.Errorf("ciphertext too short")
 	}
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("ciphertext is not a multiple of the block size")
+	}
 
 	// 提取 IV（前 16 字节）
 	iv := data[:aes.BlockSize]
@@ -15,10 +15,15 @@ type Encryptor interface {
 
 // NewEncryptor 创建加密器
 func NewEncryptor(cfg *config.ClientConfig) (Encryptor, error) {
-	switch cfg.Crypto.Method {
+	return newEncryptorForKey(cfg.Crypto.Method, cfg.Crypto.AESKey, cfg.Crypto.AESIVSeed)
+}
+
+// newEncryptorForKey 根据加密方法、密钥和 IV 种子创建加密器
+func newEncryptorForKey(method, aesKey, aesIVSeed string) (Encryptor, error) {
+	switch method {
 	case "aes":
 		// 解码 AES 密钥
-		key, err := hex.DecodeString(cfg.Crypto.AESKey)
+		key, err := hex.DecodeString(aesKey)
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode AES key: %v", err)
 		}
@@ -29,7 +34,7 @@ func NewEncryptor(cfg *config.ClientConfig) (Encryptor, error) {
 		}
 
 		// 解码 IV 种子
-		ivSeed := []byte(cfg.Crypto.AESIVSeed)
+		ivSeed := []byte(aesIVSeed)
 		if len(ivSeed) != 16 {
 			// 使用 SHA-256 调整 IV 种子长度
 			hash := sha256.Sum256(ivSeed)
@@ -40,6 +45,6 @@ func NewEncryptor(cfg *config.ClientConfig) (Encryptor, error) {
 	case "ecc":
 		return nil, fmt.Errorf("ECC encryption is no longer supported")
 	default:
-		return nil, fmt.Errorf("unsupported encryption method: %s", cfg.Crypto.Method)
+		return nil, fmt.Errorf("unsupported encryption method: %s", method)
 	}
 } 
\ No newline at end of file
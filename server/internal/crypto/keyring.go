@@ -0,0 +1,82 @@
+package crypto
+
+import (
+	"fmt"
+	"time"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/models"
+)
+
+// Key 密钥环中的一代密钥
+type Key struct {
+	Generation int
+	Encryptor  Encryptor
+	RetireAt   time.Time // 零值表示当前密钥，不会退役
+}
+
+// KeyRing 客户端配置的当前密钥和轮换保留的旧密钥
+type KeyRing struct {
+	keys []Key // 当前密钥在最前面
+}
+
+// NewKeyRing 根据客户端配置创建密钥环
+func NewKeyRing(cfg *config.ClientConfig) (*KeyRing, error) {
+	current, err := NewEncryptor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ring := &KeyRing{keys: []Key{{Generation: cfg.Crypto.Generation, Encryptor: current}}}
+
+	for _, previous := range cfg.Crypto.PreviousKeys {
+		encryptor, err := newEncryptorForKey(cfg.Crypto.Method, previous.AESKey, previous.AESIVSeed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous key for generation %d: %v", previous.Generation, err)
+		}
+		ring.keys = append(ring.keys, Key{
+			Generation: previous.Generation,
+			Encryptor:  encryptor,
+			RetireAt:   previous.RetireAt,
+		})
+	}
+	return ring, nil
+}
+
+// Current 返回用于签发新令牌的当前密钥
+func (r *KeyRing) Current() Key {
+	return r.keys[0]
+}
+
+// Open 依次尝试当前密钥和未退役的旧密钥解密令牌，返回令牌和解密它的密钥代数
+func (r *KeyRing) Open(data []byte) (*models.Token, int, error) {
+	now := time.Now()
+	var firstErr error
+	for _, key := range r.keys {
+		if !key.RetireAt.IsZero() && !now.Before(key.RetireAt) {
+			continue
+		}
+
+		token, err := openToken(key.Encryptor, data)
+		if err == nil {
+			return token, key.Generation, nil
+		}
+		// 报告当前密钥的错误，旧密钥解密失败是预期情况
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, 0, firstErr
+}
+
+// openToken 解密并反序列化令牌
+func openToken(encryptor Encryptor, data []byte) (*models.Token, error) {
+	decrypted, err := encryptor.Decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token: %v", err)
+	}
+	token := &models.Token{}
+	if err := token.Deserialize(decrypted); err != nil {
+		return nil, fmt.Errorf("failed to parse token data: %v", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,32 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"relayapi/server/internal/models"
+)
+
+// EncryptToken 序列化并加密令牌，返回不带填充的 base64url 字符串
+func EncryptToken(encryptor Encryptor, token *models.Token) (string, error) {
+	data, err := token.Serialize()
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize token: %v", err)
+	}
+	encrypted, err := encryptor.Encrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encrypted), nil
+}
+
+// DecodeToken 解码带或不带填充的 base64url 令牌字符串
+func DecodeToken(encoded string) ([]byte, error) {
+	encoded = strings.TrimRight(strings.TrimSpace(encoded), "=")
+	tokenBytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("token must be base64url encoded: %v", err)
+	}
+	return tokenBytes, nil
+}
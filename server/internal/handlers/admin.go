@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/middleware"
+	"relayapi/server/internal/middleware/logger"
+	"relayapi/server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// AdminHandler 处理运行时管理接口
+type AdminHandler struct {
+	cfg           *config.Config
+	globalLimiter *rate.Limiter
+	ipLimiter     *middleware.IPRateLimiter
+}
+
+// NewAdminHandler 创建管理接口处理器
+func NewAdminHandler(cfg *config.Config, globalLimiter *rate.Limiter, ipLimiter *middleware.IPRateLimiter) *AdminHandler {
+	return &AdminHandler{
+		cfg:           cfg,
+		globalLimiter: globalLimiter,
+		ipLimiter:     ipLimiter,
+	}
+}
+
+// RegisterRoutes 注册管理接口路由
+func (h *AdminHandler) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/clients", h.ListClients)
+	group.POST("/clients", h.AddClient)
+	group.DELETE("/clients/:hash", h.RemoveClient)
+	group.GET("/tokens/usage", h.ListUsage)
+	group.GET("/tokens/:id/usage", h.GetUsage)
+	group.DELETE("/tokens/:id/usage", h.ResetUsage)
+	group.GET("/rate-limit", h.GetRateLimit)
+	group.PUT("/rate-limit", h.UpdateRateLimit)
+	group.GET("/logs", h.RecentLogs)
+}
+
+// clientSummary 客户端配置摘要，不包含完整密钥
+type clientSummary struct {
+	Hash     string `json:"hash"`
+	Version  string `json:"version"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	BasePath string `json:"base_path"`
+	Method   string `json:"method"`
+	AESKey   string `json:"aes_key"` // 脱敏后的密钥
+}
+
+// ListClients 列出所有 .rai 客户端配置
+func (h *AdminHandler) ListClients(c *gin.Context) {
+	clients := h.cfg.ClientConfigs()
+	summaries := make([]clientSummary, 0, len(clients))
+	for hash, client := range clients {
+		summaries = append(summaries, clientSummary{
+			Hash:     hash,
+			Version:  client.Version,
+			Host:     client.Server.Host,
+			Port:     client.Server.Port,
+			BasePath: client.Server.BasePath,
+			Method:   client.Crypto.Method,
+			AESKey:   maskSecret(client.Crypto.AESKey),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Hash < summaries[j].Hash })
+	c.JSON(http.StatusOK, gin.H{"clients": summaries})
+}
+
+// AddClient 添加客户端配置，请求体为空时按服务器地址生成新的配置
+func (h *AdminHandler) AddClient(c *gin.Context) {
+	var client config.ClientConfig
+	if c.Request.ContentLength == 0 {
+		generated, err := config.DefaultClientConfig(h.cfg.Server.Server.Host, h.cfg.Server.Server.Port)
+		if err != nil {
+			apierror.JSON(c, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to generate client config: %v", err))
+			return
+		}
+		client = generated
+	} else if err := c.ShouldBindJSON(&client); err != nil {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_request_body", fmt.Sprintf("Invalid client config: %v", err))
+		return
+	}
+
+	if err := config.ValidateClientConfig(&client); err != nil {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_client_config", err.Error())
+		return
+	}
+
+	hash := h.cfg.AddClientConfig(client)
+	// 返回完整配置，调用方需要保存为 .rai 文件
+	c.JSON(http.StatusCreated, gin.H{"hash": hash, "client": client})
+}
+
+// RemoveClient 删除客户端配置，使用该配置签发的令牌立即失效
+func (h *AdminHandler) RemoveClient(c *gin.Context) {
+	hash := c.Param("hash")
+	if len(h.cfg.ClientConfigs()) == 1 {
+		if _, ok := h.cfg.GetClientConfig(hash); ok {
+			apierror.JSON(c, http.StatusConflict, "last_client", "Cannot remove the last client config")
+			return
+		}
+	}
+	if !h.cfg.RemoveClientConfig(hash) {
+		apierror.JSON(c, http.StatusNotFound, "client_not_found", fmt.Sprintf("Client config %s not found", hash))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"hash": hash, "removed": true})
+}
+
+// ListUsage 列出所有令牌的使用次数
+func (h *AdminHandler) ListUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"usage": models.AllUsage()})
+}
+
+// GetUsage 查看令牌的使用次数
+func (h *AdminHandler) GetUsage(c *gin.Context) {
+	token := &models.Token{ID: c.Param("id")}
+	c.JSON(http.StatusOK, gin.H{"id": token.ID, "used_calls": token.GetUsage()})
+}
+
+// ResetUsage 重置令牌的使用次数
+func (h *AdminHandler) ResetUsage(c *gin.Context) {
+	token := &models.Token{ID: c.Param("id")}
+	previous := token.GetUsage()
+	token.ResetUsage()
+	c.JSON(http.StatusOK, gin.H{"id": token.ID, "used_calls": 0, "previous_calls": previous})
+}
+
+// rateLimitSettings 限流设置
+type rateLimitSettings struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+	IPLimit           struct {
+		RequestsPerSecond float64 `json:"requests_per_second"`
+		Burst             int     `json:"burst"`
+	} `json:"ip_limit"`
+}
+
+// currentRateLimit 返回当前生效的限流设置
+func (h *AdminHandler) currentRateLimit() rateLimitSettings {
+	var settings rateLimitSettings
+	settings.RequestsPerSecond = float64(h.globalLimiter.Limit())
+	settings.Burst = h.globalLimiter.Burst()
+	ipRate, ipBurst := h.ipLimiter.Limit()
+	settings.IPLimit.RequestsPerSecond = float64(ipRate)
+	settings.IPLimit.Burst = ipBurst
+	return settings
+}
+
+// GetRateLimit 查看当前的限流设置
+func (h *AdminHandler) GetRateLimit(c *gin.Context) {
+	c.JSON(http.StatusOK, h.currentRateLimit())
+}
+
+// UpdateRateLimit 调整限流设置，未提供的字段保持不变
+func (h *AdminHandler) UpdateRateLimit(c *gin.Context) {
+	settings := h.currentRateLimit()
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_request_body", fmt.Sprintf("Invalid rate limit: %v", err))
+		return
+	}
+	if settings.RequestsPerSecond <= 0 || settings.Burst <= 0 || settings.IPLimit.RequestsPerSecond <= 0 || settings.IPLimit.Burst <= 0 {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_rate_limit", "Rate limits and bursts must be positive")
+		return
+	}
+
+	h.globalLimiter.SetLimit(rate.Limit(settings.RequestsPerSecond))
+	h.globalLimiter.SetBurst(settings.Burst)
+	h.ipLimiter.SetLimit(rate.Limit(settings.IPLimit.RequestsPerSecond), settings.IPLimit.Burst)
+	c.JSON(http.StatusOK, h.currentRateLimit())
+}
+
+// RecentLogs 查看最近的请求和响应日志
+func (h *AdminHandler) RecentLogs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"logs": logger.RecentEntries(limit)})
+}
+
+// maskSecret 只保留密钥首尾几位
+func maskSecret(secret string) string {
+	if len(secret) <= 12 {
+		return "***"
+	}
+	return secret[:8] + "..." + secret[len(secret)-4:]
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/middleware"
+	"relayapi/server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func newAdminRouter(t *testing.T) (*gin.Engine, *config.Config, *rate.Limiter, *middleware.IPRateLimiter) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Server.Server.Host = "http://localhost"
+	cfg.Server.Server.Port = 8840
+	client, err := config.DefaultClientConfig("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.AddClientConfig(client)
+
+	globalLimiter := rate.NewLimiter(10, 20)
+	ipLimiter := middleware.NewIPRateLimiter(1, 5)
+
+	router := gin.New()
+	admin := router.Group("/admin")
+	admin.Use(middleware.AdminAuth("secret"))
+	NewAdminHandler(cfg, globalLimiter, ipLimiter).RegisterRoutes(admin)
+	return router, cfg, globalLimiter, ipLimiter
+}
+
+func adminRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminAuth(t *testing.T) {
+	router, _, _, _ := newAdminRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+	req.Header.Set("X-Admin-Token", "wrong")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestAdminClients(t *testing.T) {
+	router, cfg, _, _ := newAdminRouter(t)
+
+	// 空请求体时生成新的客户端配置
+	w := adminRequest(router, http.MethodPost, "/admin/clients", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Hash   string              `json:"hash"`
+		Client config.ClientConfig `json:"client"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.GetClientConfig(created.Hash); !ok {
+		t.Fatal("Expected created client to be registered")
+	}
+
+	// 列表中的密钥已脱敏
+	w = adminRequest(router, http.MethodGet, "/admin/clients", "")
+	var list struct {
+		Clients []clientSummary `json:"clients"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Clients) != 2 {
+		t.Fatalf("Expected 2 clients, got %d", len(list.Clients))
+	}
+	for _, client := range list.Clients {
+		if client.AESKey == created.Client.Crypto.AESKey {
+			t.Error("Expected AES key to be masked")
+		}
+	}
+
+	// 无效配置被拒绝
+	w = adminRequest(router, http.MethodPost, "/admin/clients", `{"crypto":{"method":"des"}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid client, got %d", w.Code)
+	}
+
+	w = adminRequest(router, http.MethodDelete, "/admin/clients/"+created.Hash, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if _, ok := cfg.GetClientConfig(created.Hash); ok {
+		t.Error("Expected client to be removed")
+	}
+
+	// 不允许删除最后一个客户端配置
+	for hash := range cfg.ClientConfigs() {
+		w = adminRequest(router, http.MethodDelete, "/admin/clients/"+hash, "")
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status 409 for last client, got %d", w.Code)
+		}
+	}
+
+	w = adminRequest(router, http.MethodDelete, "/admin/clients/unknown", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestAdminResetUsage(t *testing.T) {
+	router, _, _, _ := newAdminRouter(t)
+
+	token := &models.Token{ID: "admin-test-token", MaxCalls: 10}
+	token.IncrementUsage()
+	token.IncrementUsage()
+
+	w := adminRequest(router, http.MethodGet, "/admin/tokens/admin-test-token/usage", "")
+	if !strings.Contains(w.Body.String(), `"used_calls":2`) {
+		t.Fatalf("Expected usage 2, got %s", w.Body.String())
+	}
+
+	w = adminRequest(router, http.MethodDelete, "/admin/tokens/admin-test-token/usage", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if usage := token.GetUsage(); usage != 0 {
+		t.Errorf("Expected usage to be reset, got %d", usage)
+	}
+}
+
+func TestAdminRateLimit(t *testing.T) {
+	router, _, globalLimiter, ipLimiter := newAdminRouter(t)
+
+	w := adminRequest(router, http.MethodPut, "/admin/rate-limit", `{"requests_per_second":50,"ip_limit":{"burst":8}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if globalLimiter.Limit() != 50 || globalLimiter.Burst() != 20 {
+		t.Errorf("Unexpected global limit %v/%d", globalLimiter.Limit(), globalLimiter.Burst())
+	}
+	if r, b := ipLimiter.Limit(); r != 1 || b != 8 {
+		t.Errorf("Unexpected IP limit %v/%d", r, b)
+	}
+
+	w = adminRequest(router, http.MethodPut, "/admin/rate-limit", `{"burst":0}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestBody 待转发的请求体
+type requestBody struct {
+	data   []byte    // 已缓冲的 JSON 请求体，流式转发时为 nil
+	reader io.Reader // 流式转发的请求体
+	length int64     // 流式请求体长度，-1 表示未知
+}
+
+// buffered 请求体是否已缓冲，只有缓冲的请求体才能被修改
+func (b *requestBody) buffered() bool {
+	return b.reader == nil
+}
+
+// readRequestBody 缓冲不超过阈值的 JSON 请求体，其余请求体保持流式以避免占用内存
+func (h *APIHandler) readRequestBody(c *gin.Context) (*requestBody, error) {
+	r := c.Request
+	if r.Body == nil || r.Body == http.NoBody {
+		return &requestBody{data: []byte{}}, nil
+	}
+	if !isJSONContent(r.Header.Get("Content-Type")) || r.ContentLength > h.bodyThreshold {
+		return &requestBody{reader: r.Body, length: r.ContentLength}, nil
+	}
+
+	// 长度未知时最多读取阈值 + 1 字节，超过阈值则把已读部分拼回流中
+	prefix, err := io.ReadAll(io.LimitReader(r.Body, h.bodyThreshold+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(prefix)) <= h.bodyThreshold {
+		return &requestBody{data: prefix}, nil
+	}
+	return &requestBody{
+		reader: io.MultiReader(bytes.NewReader(prefix), r.Body),
+		length: r.ContentLength,
+	}, nil
+}
+
+// shouldBufferResponse 只缓冲长度已知且不超过阈值的 JSON/文本响应
+func (h *APIHandler) shouldBufferResponse(resp *http.Response) bool {
+	if resp.ContentLength < 0 || resp.ContentLength > h.bodyThreshold {
+		return false
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if isJSONContent(contentType) {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && strings.HasPrefix(mediaType, "text/")
+}
+
+// isJSONContent 判断内容类型是否为 JSON，未声明类型的请求体按 JSON 处理
+func isJSONContent(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isStreamRequest 判断 JSON 请求体是否要求流式响应
+func isStreamRequest(body []byte) bool {
+	var req struct {
+		Stream bool `json:"stream"`
+	}
+	return json.Unmarshal(body, &req) == nil && req.Stream
+}
+
+// isBodyTooLarge 判断错误是否由请求体超出限制引起
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
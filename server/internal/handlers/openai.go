@@ -1,11 +1,20 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"relayapi/server/internal/adapters"
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/cache"
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/logging"
 	"relayapi/server/internal/models"
 	"relayapi/server/internal/services"
 	"relayapi/server/internal/utils"
@@ -16,14 +25,28 @@ import (
 // APIHandler 处理 API 请求
 type APIHandler struct {
 	proxyService   *services.ProxyService
+	headerPolicy   *services.HeaderPolicy
 	tokenProcessor *TokenProcessor
+	responseCache  *cache.Cache         // 未启用缓存时为 nil
+	semanticCache  *cache.SemanticCache // 未启用语义缓存时为 nil
+	embeddings     *services.EmbeddingClient
+	coalescer      *services.Coalescer // 未启用请求合并时为 nil
+	serverConfig   *config.ServerConfig
+	bodyThreshold  int64 // 缓冲 JSON 请求体的最大字节数
 }
 
-// NewAPIHandler 创建新的 API 处理器
-func NewAPIHandler(proxyService *services.ProxyService) *APIHandler {
+// NewAPIHandler 创建新的 API 处理器，responseCache 为 nil 时不缓存响应
+func NewAPIHandler(proxyService *services.ProxyService, responseCache *cache.Cache, cfg *config.Config) *APIHandler {
 	return &APIHandler{
 		proxyService:   proxyService,
+		headerPolicy:   services.NewHeaderPolicy(&cfg.Server),
 		tokenProcessor: &TokenProcessor{},
+		responseCache:  responseCache,
+		semanticCache:  cache.NewSemantic(&cfg.Server),
+		embeddings:     services.NewEmbeddingClient(proxyService, &cfg.Server),
+		coalescer:      services.NewCoalescer(&cfg.Server),
+		serverConfig:   &cfg.Server,
+		bodyThreshold:  cfg.Server.BodyProcessThreshold(),
 	}
 }
 
@@ -37,27 +60,26 @@ func (h *APIHandler) HandleRequest(c *gin.Context) {
 	// 获取请求路径
 	path := c.Param("path")
 	if path == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid API path",
-		})
+		apierror.JSON(c, http.StatusBadRequest, "invalid_path", "Invalid API path")
 		return
 	}
 
-	// 读取请求体
-	body, err := io.ReadAll(c.Request.Body)
+	// 读取请求体，大文件和非 JSON 请求体保持流式
+	reqBody, err := h.readRequestBody(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("Failed to read request body: %v", err),
-		})
+		status := http.StatusBadRequest
+		if isBodyTooLarge(err) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		apierror.JSON(c, status, "invalid_request_body", fmt.Sprintf("Failed to read request body: %v", err))
 		return
 	}
+	body := reqBody.data
 
 	// 从上下文中获取令牌和提供者信息
 	token, exists := c.Get("token")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Token not found in context",
-		})
+		apierror.JSON(c, http.StatusInternalServerError, "internal_error", "Token not found in context")
 		return
 	}
 
@@ -66,15 +88,15 @@ func (h *APIHandler) HandleRequest(c *gin.Context) {
 	apiKey := tokenObj.APIKey
 	provider := tokenObj.Provider
 
-	// 处理请求体，根据令牌的扩展信息进行修改
-	processedBody, err := h.tokenProcessor.ProcessRequestBody(tokenObj, body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to process request body: %v", err),
-		})
-		return
+	// 处理请求体，根据令牌的扩展信息进行修改（仅限已缓冲的 JSON 请求体）
+	if reqBody.buffered() {
+		processedBody, err := h.tokenProcessor.ProcessRequestBody(tokenObj, body)
+		if err != nil {
+			apierror.JSON(c, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to process request body: %v", err))
+			return
+		}
+		body = processedBody
 	}
-	body = processedBody
 
 	// 构建目标 URL
 	baseURL := h.getBaseURL(provider)
@@ -91,32 +113,71 @@ func (h *APIHandler) HandleRequest(c *gin.Context) {
 		targetURL = fmt.Sprintf("%s/%s", baseURL, extPath)
 	}
 
-	// 转发请求，保留原始请求头
-	headers := make(map[string]string)
-	for key, values := range c.Request.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
+	// 流式请求只受代理的首字节、空闲和总时长超时限制，转发前清除全局写超时
+	if reqBody.buffered() && isStreamRequest(body) {
+		services.ClearWriteDeadline(c)
+	}
+
+	// 需要格式转换时，将 OpenAI 请求转换为上游格式
+	adapter, err := h.resolveAdapter(tokenObj, path)
+	if err != nil {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_adapter", fmt.Sprintf("Failed to resolve adapter: %v", err))
+		return
+	}
+	var adaptedReq *adapters.Request
+	if adapter != nil {
+		if !reqBody.buffered() {
+			apierror.JSON(c, http.StatusRequestEntityTooLarge, "request_too_large", "Request body is too large to convert")
+			return
 		}
+		adaptedReq, err = adapter.ConvertRequest(body)
+		if err != nil {
+			apierror.JSON(c, http.StatusBadRequest, "invalid_request_body", fmt.Sprintf("Failed to convert request: %v", err))
+			return
+		}
+		body = adaptedReq.Body
+		targetURL = fmt.Sprintf("%s/%s", baseURL, adaptedReq.Path)
 	}
-	// 设置 Authorization 头
-	headers["Authorization"] = fmt.Sprintf("Bearer %s", apiKey)
 
-	// fmt.Printf("Provider: %s, Target URL: %s\n", provider, targetURL)
+	// 按转发策略过滤原始请求头
+	headers := h.headerPolicy.OutboundHeaders(c.Request, c.ClientIP())
+	// 设置 Authorization 头
+	headers.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	if adapter != nil {
+		adapter.SetAuthHeaders(headers, apiKey)
+	}
 
-	resp, err := h.proxyService.ProxyRequest(c.Request.Method, targetURL, headers, body)
+	// 上游请求绑定客户端请求的上下文，客户端断开时立即取消
+	ctx := c.Request.Context()
+	logging.FromContext(ctx).Debug("Proxying request", "provider", provider, "target_url", targetURL,
+		"buffered", reqBody.buffered())
+	var resp *http.Response
+	if reqBody.buffered() {
+		resp, err = h.proxyBufferedRequest(c, tokenObj, path, targetURL, headers, body)
+	} else {
+		resp, err = h.proxyService.ProxyStreamRequest(ctx, c.Request.Method, targetURL, headers, reqBody.reader, reqBody.length)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to proxy request: %v", err),
-		})
+		if clientCanceled(c, err) {
+			return
+		}
+		if isBodyTooLarge(err) {
+			apierror.JSON(c, http.StatusRequestEntityTooLarge, "request_too_large", fmt.Sprintf("Failed to proxy request: %v", err))
+			return
+		}
+		status, code := upstreamErrorStatus(err)
+		logging.FromContext(ctx).Warn("Failed to proxy request", "provider", provider, "code", code, "error", err)
+		apierror.JSON(c, status, code, fmt.Sprintf("Failed to proxy request: %v", err))
 		return
 	}
 
-	// 设置响应头
-	for key, values := range resp.Header {
-		for _, value := range values {
-			c.Header(key, value)
-		}
+	if adapter != nil {
+		h.handleAdaptedResponse(c, adapter, adaptedReq, resp)
+		return
 	}
+
+	// 设置响应头
+	h.headerPolicy.CopyResponseHeaders(c.Writer.Header(), resp.Header)
 	// 设置跨域头
 	origin := c.GetHeader("Origin")
 	if origin == "" {
@@ -126,11 +187,26 @@ func (h *APIHandler) HandleRequest(c *gin.Context) {
 
 	// 检查是否为流式响应
 	if strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
-		err = h.proxyService.HandleStreamResponse(c, resp)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("Failed to handle stream response: %v", err),
-			})
+		if err := h.proxyService.HandleStreamResponse(ctx, c, resp, nil); err != nil {
+			streamError(c, err)
+		}
+		return
+	}
+
+	// 二进制、分块或较大的响应体（TTS 音频、图片、NDJSON 等）直接按字节转发
+	if !h.shouldBufferResponse(resp) {
+		if err := h.proxyService.CopyResponse(ctx, c, resp); err != nil {
+			if clientCanceled(c, err) {
+				return
+			}
+			status, code := upstreamErrorStatus(err)
+			if !c.Writer.Written() {
+				apierror.JSON(c, status, code, fmt.Sprintf("Failed to copy response: %v", err))
+				return
+			}
+			// 响应体已部分写出，只能中断连接并记录实际状态
+			c.Error(err)
+			apierror.SetStatus(c, status)
 		}
 		return
 	}
@@ -138,12 +214,217 @@ func (h *APIHandler) HandleRequest(c *gin.Context) {
 	// 非流式响应处理
 	respBody, err := h.proxyService.ReadResponse(resp)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to read response: %v", err),
-		})
+		if clientCanceled(c, err) {
+			return
+		}
+		apierror.JSON(c, http.StatusBadGateway, "upstream_error", fmt.Sprintf("Failed to read response: %v", err))
 		return
 	}
 
 	// 返回响应
 	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
 }
+
+// proxyBufferedRequest 转发已缓冲的请求，启用缓存时优先返回未过期的缓存响应
+func (h *APIHandler) proxyBufferedRequest(c *gin.Context, token *models.Token, path, targetURL string, headers http.Header, body []byte) (*http.Response, error) {
+	// Cache-Control: no-cache 跳过读取，no-store 同时跳过写入
+	skipLookup, skipStore := cache.Bypass(c.Request.Header)
+
+	// 精确匹配缓存，只用于确定性请求
+	var exactKey string
+	var exactTTL time.Duration
+	if h.responseCache != nil && cache.Deterministic(body) {
+		exactTTL = time.Duration(h.serverConfig.CacheTTL(path)) * time.Second
+	}
+	if exactTTL > 0 {
+		exactKey = cache.Key(token.Provider, c.Request.Method, targetURL, body)
+		if !skipLookup {
+			if resp, ok := h.responseCache.Lookup(exactKey); ok {
+				setCacheStatus(c, cache.StatusHit)
+				return resp, nil
+			}
+		}
+	}
+
+	// 语义缓存，需要令牌扩展信息中启用 semantic_cache
+	semanticScope, vector := h.semanticVector(c, token, targetURL, body)
+	if vector != nil && !skipLookup {
+		if resp, score, ok := h.semanticCache.Lookup(semanticScope, vector); ok {
+			setCacheStatus(c, cache.StatusSemanticHit)
+			c.Header("X-RelayAPI-Cache-Similarity", strconv.FormatFloat(score, 'f', 4, 64))
+			return resp, nil
+		}
+	}
+
+	resp, err := h.coalesceRequest(c, token.Provider, targetURL, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	if exactKey == "" && vector == nil {
+		return resp, nil
+	}
+	setCacheStatus(c, cache.StatusMiss)
+	if skipStore {
+		return resp, nil
+	}
+	if exactKey != "" {
+		h.responseCache.Record(exactKey, exactTTL, resp)
+	}
+	if vector != nil {
+		h.semanticCache.Record(semanticScope, vector, resp)
+	}
+	return resp, nil
+}
+
+// coalesceRequest 转发请求，相同的并发非流式请求只向上游发送一次
+func (h *APIHandler) coalesceRequest(c *gin.Context, provider, targetURL string, headers http.Header, body []byte) (*http.Response, error) {
+	ctx := c.Request.Context()
+	fetch := func() (*http.Response, error) {
+		return h.proxyService.ProxyRequest(ctx, c.Request.Method, targetURL, headers, body)
+	}
+	if h.coalescer == nil || isStreamRequest(body) {
+		return fetch()
+	}
+
+	key := cache.Key(provider, c.Request.Method, targetURL, body)
+	resp, shared, err := h.coalescer.Do(ctx, key, fetch)
+	if shared {
+		c.Set(services.CoalescedKey, true)
+	}
+	return resp, err
+}
+
+// semanticVector 计算对话请求提示词的嵌入向量，未启用语义缓存或计算失败时返回 nil
+func (h *APIHandler) semanticVector(c *gin.Context, token *models.Token, targetURL string, body []byte) (string, []float32) {
+	if h.semanticCache == nil || c.Request.Method != http.MethodPost {
+		return "", nil
+	}
+	if extInfo, err := h.tokenProcessor.ParseExtInfo(token); err != nil || !extInfo.SemanticCache {
+		return "", nil
+	}
+	// 提供商没有 embeddings 接口时跳过语义缓存，避免每个请求都多一次注定失败的上游调用
+	if !h.embeddings.Supports(token.Provider) {
+		return "", nil
+	}
+	prompt, scope, ok := cache.Prompt(body)
+	if !ok {
+		return "", nil
+	}
+
+	vector, err := h.embeddings.Embed(c.Request.Context(), token.Provider, token.APIKey, prompt)
+	if err != nil {
+		// 嵌入失败不影响正常转发
+		c.Error(fmt.Errorf("semantic cache: %v", err))
+		return "", nil
+	}
+	return token.Provider + "\n" + targetURL + "\n" + scope, vector
+}
+
+// setCacheStatus 记录缓存命中情况，供日志和统计使用，并通过响应头告知客户端
+func setCacheStatus(c *gin.Context, status string) {
+	c.Set(cache.StatusKey, status)
+	c.Header("X-RelayAPI-Cache", status)
+}
+
+// resolveAdapter 根据令牌扩展信息或提供商选择格式转换适配器
+func (h *APIHandler) resolveAdapter(token *models.Token, path string) (adapters.Adapter, error) {
+	extInfo, err := h.tokenProcessor.ParseExtInfo(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var adapter adapters.Adapter
+	if extInfo.Adapter != "" {
+		var ok bool
+		adapter, ok = adapters.Get(extInfo.Adapter)
+		if !ok {
+			return nil, fmt.Errorf("unknown adapter: %s", extInfo.Adapter)
+		}
+	} else if providerAdapter, ok := adapters.ForProvider(token.Provider); ok {
+		adapter = providerAdapter
+	}
+
+	if adapter == nil || !adapter.Supports(path) {
+		return nil, nil
+	}
+	return adapter, nil
+}
+
+// handleAdaptedResponse 将上游响应转换回 OpenAI 格式
+func (h *APIHandler) handleAdaptedResponse(c *gin.Context, adapter adapters.Adapter, req *adapters.Request, resp *http.Response) {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		origin = "*"
+	}
+	c.Header("Access-Control-Allow-Origin", origin)
+
+	// 流式响应逐条转换
+	if resp.StatusCode < http.StatusBadRequest && strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		if err := h.proxyService.HandleStreamResponse(c.Request.Context(), c, resp, adapter.NewStreamConverter(req)); err != nil {
+			streamError(c, err)
+		}
+		return
+	}
+
+	respBody, err := h.proxyService.ReadResponse(resp)
+	if err != nil {
+		if clientCanceled(c, err) {
+			return
+		}
+		apierror.JSON(c, http.StatusBadGateway, "upstream_error", fmt.Sprintf("Failed to read response: %v", err))
+		return
+	}
+
+	// 错误响应转换为 OpenAI 错误格式
+	if resp.StatusCode >= http.StatusBadRequest {
+		c.Data(resp.StatusCode, "application/json", adapter.ConvertError(respBody))
+		return
+	}
+
+	converted, err := adapter.ConvertResponse(req, bytes.TrimSpace(respBody))
+	if err != nil {
+		apierror.JSON(c, http.StatusBadGateway, "upstream_error", fmt.Sprintf("Failed to convert response: %v", err))
+		return
+	}
+	c.Data(resp.StatusCode, "application/json", converted)
+}
+
+// clientCanceled 判断错误是否由客户端断开引起，是则记录取消状态并停止写出响应
+func clientCanceled(c *gin.Context, err error) bool {
+	if !errors.Is(err, context.Canceled) || c.Request.Context().Err() == nil {
+		return false
+	}
+	c.Error(err)
+	if !c.Writer.Written() {
+		c.Status(services.StatusClientClosedRequest)
+	}
+	apierror.SetStatus(c, services.StatusClientClosedRequest)
+	c.Abort()
+	return true
+}
+
+// upstreamErrorStatus 根据上游错误确定返回给客户端的状态码和错误码
+func upstreamErrorStatus(err error) (int, string) {
+	if services.IsTimeout(err) {
+		return http.StatusGatewayTimeout, "upstream_timeout"
+	}
+	return http.StatusBadGateway, "upstream_error"
+}
+
+// streamError 处理 SSE 转发过程中的错误。响应头已发送时以 SSE 错误事件结束流，
+// 避免在 text/event-stream 响应后追加 JSON
+func streamError(c *gin.Context, err error) {
+	if clientCanceled(c, err) {
+		return
+	}
+	c.Error(err)
+	status, code := upstreamErrorStatus(err)
+	message := fmt.Sprintf("Failed to handle stream response: %v", err)
+	if !c.Writer.Written() {
+		apierror.JSON(c, status, code, message)
+		return
+	}
+	if writeErr := apierror.WriteEvent(c, status, code, message); writeErr != nil {
+		c.Error(writeErr)
+	}
+}
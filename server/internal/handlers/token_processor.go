@@ -10,7 +10,19 @@ type TokenProcessor struct{}
 
 // ExtInfoData 扩展信息的数据结构
 type ExtInfoData struct {
-	RepM string `json:"rep_m,omitempty"`
+	RepM          string `json:"rep_m,omitempty"`
+	Adapter       string `json:"adapter,omitempty"`        // 请求格式转换适配器，如 anthropic
+	SemanticCache bool   `json:"semantic_cache,omitempty"` // 是否使用语义缓存
+}
+
+// ParseExtInfo 解析令牌的扩展信息
+func (p *TokenProcessor) ParseExtInfo(token *models.Token) (ExtInfoData, error) {
+	var extInfo ExtInfoData
+	if token.ExtInfo == "" {
+		return extInfo, nil
+	}
+	err := json.Unmarshal([]byte(token.ExtInfo), &extInfo)
+	return extInfo, err
 }
 
 // ProcessRequestBody 处理请求体，根据扩展信息进行修改
@@ -20,8 +32,8 @@ func (p *TokenProcessor) ProcessRequestBody(token *models.Token, requestBody []b
 	}
 
 	// 解析扩展信息
-	var extInfo ExtInfoData
-	if err := json.Unmarshal([]byte(token.ExtInfo), &extInfo); err != nil {
+	extInfo, err := p.ParseExtInfo(token)
+	if err != nil {
 		return nil, err
 	}
 
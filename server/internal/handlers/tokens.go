@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/crypto"
+	"relayapi/server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 签发令牌的默认参数，与各语言 SDK 保持一致
+const (
+	DefaultTokenMaxCalls = 100
+	DefaultTokenExpire   = 24 * 60 * 60 // 秒
+)
+
+// TokenHandler 在服务端签发访问令牌，后端无需依赖 SDK
+type TokenHandler struct {
+	cfg *config.Config
+}
+
+// NewTokenHandler 创建令牌签发处理器
+func NewTokenHandler(cfg *config.Config) *TokenHandler {
+	return &TokenHandler{cfg: cfg}
+}
+
+// mintRequest 签发令牌的请求参数
+type mintRequest struct {
+	APIKey   string          `json:"api_key"`
+	KeyAlias string          `json:"key_alias"` // 使用配置中的 API Key 别名代替 api_key
+	Provider string          `json:"provider"`
+	MaxCalls int             `json:"max_calls"`
+	Expire   int             `json:"expire"`   // 有效期（秒）
+	ExtInfo  json.RawMessage `json:"ext_info"` // 字符串或 JSON 对象
+	RAIHash  string          `json:"rai_hash"` // 用于加密的客户端配置，只有一个配置时可省略
+	Path     string          `json:"path"`     // 生成 URL 时附加的 API 路径，如 /chat/completions
+}
+
+// mintResponse 签发令牌的响应
+type mintResponse struct {
+	ID         string    `json:"id"`
+	Token      string    `json:"token"`
+	RAIHash    string    `json:"rai_hash"`
+	URL        string    `json:"url"`
+	Provider   string    `json:"provider"`
+	MaxCalls   int       `json:"max_calls"`
+	ExpireTime time.Time `json:"expire_time"`
+}
+
+// Mint 签发令牌，使用与 TokenAuth 相同的加密器加密
+func (h *TokenHandler) Mint(c *gin.Context) {
+	var req mintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_request_body", fmt.Sprintf("Invalid token request: %v", err))
+		return
+	}
+
+	apiKey, provider := req.APIKey, req.Provider
+	if req.KeyAlias != "" {
+		alias, ok := h.cfg.Server.Admin.KeyAliases[req.KeyAlias]
+		if !ok {
+			apierror.JSON(c, http.StatusBadRequest, "unknown_key_alias", fmt.Sprintf("Unknown key alias: %s", req.KeyAlias))
+			return
+		}
+		apiKey = alias.APIKey
+		if alias.Provider != "" {
+			provider = alias.Provider
+		}
+	}
+	if apiKey == "" || provider == "" {
+		apierror.JSON(c, http.StatusBadRequest, "missing_parameter", "api_key (or key_alias) and provider are required")
+		return
+	}
+
+	extInfo, err := extInfoString(req.ExtInfo)
+	if err != nil {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_ext_info", err.Error())
+		return
+	}
+
+	maxCalls := req.MaxCalls
+	if maxCalls == 0 {
+		maxCalls = DefaultTokenMaxCalls
+	}
+	expire := req.Expire
+	if expire == 0 {
+		expire = DefaultTokenExpire
+	}
+	if maxCalls < 0 || expire < 0 {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_parameter", "max_calls and expire must be positive")
+		return
+	}
+
+	raiHash, clientCfg, ok := h.clientConfig(req.RAIHash)
+	if !ok {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_rai_hash", "rai_hash is missing or not a known client config")
+		return
+	}
+
+	now := time.Now().UTC()
+	token := &models.Token{
+		ID:         "token-" + uuid.New().String(),
+		APIKey:     apiKey,
+		MaxCalls:   maxCalls,
+		ExpireTime: now.Add(time.Duration(expire) * time.Second),
+		CreatedAt:  now,
+		Provider:   provider,
+		ExtInfo:    extInfo,
+	}
+	encryptor, err := crypto.NewEncryptor(&clientCfg)
+	if err != nil {
+		apierror.JSON(c, http.StatusInternalServerError, "encryptor_error", fmt.Sprintf("Encryptor initialization failed: %v", err))
+		return
+	}
+	encrypted, err := crypto.EncryptToken(encryptor, token)
+	if err != nil {
+		apierror.JSON(c, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to encrypt token: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, mintResponse{
+		ID:         token.ID,
+		Token:      encrypted,
+		RAIHash:    raiHash,
+		URL:        clientCfg.APIURL(req.Path, encrypted),
+		Provider:   provider,
+		MaxCalls:   maxCalls,
+		ExpireTime: token.ExpireTime,
+	})
+}
+
+// Inspect 解密令牌并返回其内容和使用情况，不计入使用次数
+func (h *TokenHandler) Inspect(c *gin.Context) {
+	var req struct {
+		Token   string `json:"token" binding:"required"`
+		RAIHash string `json:"rai_hash"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_request_body", fmt.Sprintf("Invalid inspect request: %v", err))
+		return
+	}
+
+	_, clientCfg, ok := h.clientConfig(req.RAIHash)
+	if !ok {
+		apierror.JSON(c, http.StatusBadRequest, "invalid_rai_hash", "rai_hash is missing or not a known client config")
+		return
+	}
+	keyRing, err := crypto.NewKeyRing(&clientCfg)
+	if err != nil {
+		apierror.JSON(c, http.StatusInternalServerError, "encryptor_error", fmt.Sprintf("Encryptor initialization failed: %v", err))
+		return
+	}
+	tokenBytes, err := crypto.DecodeToken(req.Token)
+	if err != nil {
+		apierror.JSON(c, http.StatusUnprocessableEntity, "invalid_token_format", err.Error())
+		return
+	}
+	token, generation, err := keyRing.Open(tokenBytes)
+	if err != nil {
+		apierror.JSON(c, http.StatusUnprocessableEntity, "invalid_token", err.Error())
+		return
+	}
+	info := token.Info()
+	info.KeyGeneration = generation
+	c.JSON(http.StatusOK, info)
+}
+
+// clientConfig 查找用于加密的客户端配置，未指定 hash 时要求只有一个配置
+func (h *TokenHandler) clientConfig(raiHash string) (string, config.ClientConfig, bool) {
+	if raiHash != "" {
+		clientCfg, ok := h.cfg.GetClientConfig(raiHash)
+		return raiHash, clientCfg, ok
+	}
+	clients := h.cfg.ClientConfigs()
+	if len(clients) != 1 {
+		return "", config.ClientConfig{}, false
+	}
+	for hash, clientCfg := range clients {
+		return hash, clientCfg, true
+	}
+	return "", config.ClientConfig{}, false
+}
+
+// extInfoString 将 ext_info 转换为令牌中的字符串，JSON 对象会被序列化
+func extInfoString(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return "", fmt.Errorf("ext_info must be a string or JSON object")
+	}
+	return string(raw), nil
+}
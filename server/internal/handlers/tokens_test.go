@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/crypto"
+	"relayapi/server/internal/middleware"
+	"relayapi/server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMintToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	client, err := config.DefaultClientConfig("http://relay.example.com", 8840)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raiHash := cfg.AddClientConfig(client)
+	cfg.Server.Admin.KeyAliases = map[string]config.KeyAlias{
+		"team-a": {Provider: "openai", APIKey: "sk-alias"},
+	}
+
+	router := gin.New()
+	router.POST("/relayapi-admin/tokens", NewTokenHandler(cfg).Mint)
+	// 签发的令牌必须能通过 TokenAuth
+	router.GET("/relayapi/*path", middleware.TokenAuth(cfg), func(c *gin.Context) {
+		token := c.MustGet("token").(*models.Token)
+		c.JSON(http.StatusOK, token)
+	})
+
+	body := `{"key_alias":"team-a","max_calls":5,"expire":60,"ext_info":{"semantic_cache":true},"path":"/chat/completions"}`
+	req := httptest.NewRequest(http.MethodPost, "/relayapi-admin/tokens", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var minted mintResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &minted); err != nil {
+		t.Fatal(err)
+	}
+	if minted.RAIHash != raiHash {
+		t.Errorf("Expected rai_hash %s, got %s", raiHash, minted.RAIHash)
+	}
+	if !strings.HasPrefix(minted.URL, "http://relay.example.com:8840/relayapi/chat/completions?") {
+		t.Errorf("Unexpected URL: %s", minted.URL)
+	}
+
+	mintedURL, err := url.Parse(minted.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, mintedURL.RequestURI(), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected minted token to authenticate, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var token models.Token
+	if err := json.Unmarshal(w.Body.Bytes(), &token); err != nil {
+		t.Fatal(err)
+	}
+	if token.APIKey != "sk-alias" || token.Provider != "openai" || token.MaxCalls != 5 {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+	if token.ExtInfo != `{"semantic_cache":true}` {
+		t.Errorf("Unexpected ext_info: %s", token.ExtInfo)
+	}
+}
+
+func TestMintTokenValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	client, _ := config.DefaultClientConfig("", 0)
+	cfg.AddClientConfig(client)
+
+	router := gin.New()
+	router.POST("/relayapi-admin/tokens", NewTokenHandler(cfg).Mint)
+
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"missing api key", `{"provider":"openai"}`, "missing_parameter"},
+		{"unknown alias", `{"key_alias":"nope"}`, "unknown_key_alias"},
+		{"invalid ext_info", `{"api_key":"sk","provider":"openai","ext_info":[1]}`, "invalid_ext_info"},
+		{"unknown rai hash", `{"api_key":"sk","provider":"openai","rai_hash":"missing"}`, "invalid_rai_hash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/relayapi-admin/tokens", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.code) {
+				t.Errorf("Expected 400 %s, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestInspectToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	client, _ := config.DefaultClientConfig("", 0)
+	cfg.AddClientConfig(client)
+
+	encryptor, err := crypto.NewEncryptor(&client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &models.Token{
+		ID:         "inspect-test-token",
+		APIKey:     "sk-1234567890abcdef",
+		MaxCalls:   2,
+		ExpireTime: time.Now().Add(time.Hour),
+		CreatedAt:  time.Now(),
+		Provider:   "openai",
+	}
+	encrypted, err := crypto.EncryptToken(encryptor, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token.IncrementUsage()
+	token.IncrementUsage()
+	defer token.ResetUsage()
+
+	router := gin.New()
+	router.POST("/relayapi-admin/tokens/inspect", NewTokenHandler(cfg).Inspect)
+
+	req := httptest.NewRequest(http.MethodPost, "/relayapi-admin/tokens/inspect",
+		strings.NewReader(`{"token":"`+encrypted+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var info models.TokenInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Valid || info.Reason != "usage_exceeded" || info.UsedCalls != 2 {
+		t.Errorf("Unexpected token info: %+v", info)
+	}
+	if info.APIKey == token.APIKey {
+		t.Error("Expected API key to be masked")
+	}
+	// 查看令牌不计入使用次数
+	if usage := token.GetUsage(); usage != 2 {
+		t.Errorf("Expected usage to stay 2, got %d", usage)
+	}
+}
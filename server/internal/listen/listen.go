@@ -0,0 +1,49 @@
+package listen
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"relayapi/server/internal/config"
+)
+
+// Listen 按配置打开监听，systemd 类型的监听从 activation 中取出
+func Listen(l config.Listener, activation *Activation) (net.Listener, error) {
+	switch l.Network {
+	case config.NetworkSystemd:
+		return activation.Take(l.Address)
+	case config.NetworkUnix:
+		return listenUnix(l)
+	default:
+		return net.Listen("tcp", l.Address)
+	}
+}
+
+// listenUnix 创建 unix 套接字，删除上次运行残留的套接字文件并设置权限
+func listenUnix(l config.Listener) (net.Listener, error) {
+	mode, err := l.SocketFileMode()
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(l.Address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", l.Address)
+		}
+		if err := os.Remove(l.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", l.Address)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(l.Address, mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
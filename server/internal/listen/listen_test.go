@@ -0,0 +1,101 @@
+package listen
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"relayapi/server/internal/config"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relayapi.sock")
+	l := config.Listener{Network: config.NetworkUnix, Address: path, SocketMode: "0600"}
+
+	// 上次运行残留的套接字文件会被替换
+	for i := 0; i < 2; i++ {
+		stale, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		ln, err := Listen(l, &Activation{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected socket mode 0600, got %v", info.Mode().Perm())
+		}
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		ln.Close()
+	}
+
+	// 不删除不是套接字的文件
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(l, &Activation{}); err == nil {
+		t.Error("Expected regular file to be kept")
+	}
+}
+
+func TestActivation(t *testing.T) {
+	var files []*os.File
+	for i := 0; i < 2; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := ln.(*net.TCPListener).File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ln.Close()
+		files = append(files, file)
+	}
+
+	activation, err := fromFiles(files, []string{"api", "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer activation.Close()
+	if activation.Len() != 2 {
+		t.Fatalf("Expected 2 sockets, got %d", activation.Len())
+	}
+
+	admin, err := Listen(config.Listener{Network: config.NetworkSystemd, Address: "admin"}, activation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	if _, err := activation.Take("admin"); err == nil {
+		t.Error("Expected a socket to be taken only once")
+	}
+
+	// 未指定名称时使用第一个未使用的套接字
+	api, err := activation.Take("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Close()
+	conn, err := net.Dial("tcp", api.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if _, err := activation.Take(""); err == nil {
+		t.Error("Expected no unused sockets")
+	}
+}
@@ -0,0 +1,107 @@
+package listen
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// listenFDsStart systemd 传入的第一个文件描述符
+const listenFDsStart = 3
+
+// Activation systemd 套接字激活传入的监听。
+// 重启服务时套接字由 systemd 持有，新连接在新进程启动前排队而不会被拒绝
+type Activation struct {
+	mu        sync.Mutex
+	listeners []activated
+}
+
+type activated struct {
+	name string // 套接字单元中的 FileDescriptorName
+	ln   net.Listener
+	used bool
+}
+
+// Systemd 读取 LISTEN_PID、LISTEN_FDS 和 LISTEN_FDNAMES，不是由套接字激活启动时返回空的 Activation
+func Systemd() (*Activation, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return &Activation{}, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS: %v", err)
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// 不传递给子进程
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	files := make([]*os.File, count)
+	for i := range files {
+		files[i] = os.NewFile(uintptr(listenFDsStart+i), "LISTEN_FD_"+strconv.Itoa(listenFDsStart+i))
+	}
+	return fromFiles(files, names)
+}
+
+// fromFiles 将文件描述符转换为监听，names 为对应的 FileDescriptorName
+func fromFiles(files []*os.File, names []string) (*Activation, error) {
+	activation := &Activation{}
+	for i, file := range files {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		// FileListener 复制文件描述符，原文件可以关闭
+		ln, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			activation.Close()
+			return nil, fmt.Errorf("systemd socket %s is not a listening socket: %v", file.Name(), err)
+		}
+		activation.listeners = append(activation.listeners, activated{name: name, ln: ln})
+	}
+	return activation, nil
+}
+
+// Len 返回传入的套接字数量
+func (a *Activation) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.listeners)
+}
+
+// Take 取出名称为 name 的套接字，name 为空时取出第一个未使用的套接字
+func (a *Activation) Take(name string) (net.Listener, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.listeners {
+		l := &a.listeners[i]
+		if l.used || (name != "" && l.name != name) {
+			continue
+		}
+		l.used = true
+		return l.ln, nil
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no unused socket passed by systemd socket activation")
+	}
+	return nil, fmt.Errorf("no unused socket named %q passed by systemd socket activation", name)
+}
+
+// Close 关闭没有被取出的套接字
+func (a *Activation) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.listeners {
+		if !a.listeners[i].used {
+			a.listeners[i].ln.Close()
+			a.listeners[i].used = true
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// New 创建自动脱敏的结构化日志，json 为 false 时使用 key=value 文本格式
+func New(w io.Writer, json bool, level slog.Leveler) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if json {
+		handler = slog.NewJSONHandler(w, options)
+	} else {
+		handler = slog.NewTextHandler(w, options)
+	}
+	return slog.New(NewRedactHandler(handler))
+}
+
+// Discard 丢弃所有日志
+func Discard() *slog.Logger {
+	return slog.New(NewRedactHandler(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1})))
+}
+
+type contextKey struct{}
+
+// NewContext 返回携带日志的 context，日志中间件用它为每个请求注入带 request_id 的日志
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext 返回 context 中的日志，没有时返回 slog.Default()
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
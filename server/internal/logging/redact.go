@@ -0,0 +1,186 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Redacted 替换敏感值的文本
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 值需要脱敏的字段和请求头（小写，- 视为 _）
+var sensitiveKeys = map[string]bool{
+	"authorization":       true,
+	"proxy_authorization": true,
+	"cookie":              true,
+	"set_cookie":          true,
+	"token":               true,
+	"apikey":              true,
+	"password":            true,
+	"secret":              true,
+	"aes_iv_seed":         true,
+	"connection_string":   true,
+}
+
+// sensitiveSuffixes 以这些后缀结尾的字段也需要脱敏，如 api_key、aes_key、x_admin_token
+var sensitiveSuffixes = []string{"_key", "_token", "_secret", "_password"}
+
+var (
+	// JSON 文本中的敏感字段，如请求体中的 "api_key": "sk-..."
+	jsonSecretPattern = regexp.MustCompile(`(?i)("[a-z0-9_-]*(?:key|token|secret|password|authorization|iv_seed)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// URL 查询参数中的敏感字段，如 ?token=...
+	querySecretPattern = regexp.MustCompile(`(?i)\b([a-z0-9_-]*(?:key|token|secret|password))=[^&\s"']+`)
+	// Authorization 请求头的值
+	bearerPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9._~+/=-]+`)
+	// 常见的 API Key 格式
+	apiKeyPattern = regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{8,}`)
+)
+
+// IsSensitive 判断字段名或请求头名对应的值是否需要脱敏
+func IsSensitive(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString 脱敏文本中的 JSON 字段、查询参数、Bearer 令牌和 API Key
+func RedactString(s string) string {
+	s = jsonSecretPattern.ReplaceAllString(s, `$1"`+Redacted+`"`)
+	s = querySecretPattern.ReplaceAllString(s, "$1="+Redacted)
+	s = bearerPattern.ReplaceAllString(s, "$1 "+Redacted)
+	return apiKeyPattern.ReplaceAllString(s, Redacted)
+}
+
+// RedactValue 返回脱敏后的副本，递归处理 map、请求头和切片，不修改原值
+func RedactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return RedactString(v)
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = redactField(key, item)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for key, item := range v {
+			redacted[key] = redactField(key, item).(string)
+		}
+		return redacted
+	case http.Header:
+		return http.Header(redactHeader(v))
+	case map[string][]string:
+		return redactHeader(v)
+	case []string:
+		redacted := make([]string, len(v))
+		for i, item := range v {
+			redacted[i] = RedactString(item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = RedactValue(item)
+		}
+		return redacted
+	case error:
+		return RedactString(v.Error())
+	}
+	return value
+}
+
+// redactField 脱敏字段的值，敏感字段的非空值整体替换
+func redactField(key string, value interface{}) interface{} {
+	if IsSensitive(key) {
+		if s, ok := value.(string); ok {
+			if s == "" {
+				return s
+			}
+			return Redacted
+		}
+		if value != nil {
+			return Redacted
+		}
+	}
+	return RedactValue(value)
+}
+
+func redactHeader(header map[string][]string) map[string][]string {
+	redacted := make(map[string][]string, len(header))
+	for key, values := range header {
+		copied := make([]string, len(values))
+		for i, value := range values {
+			copied[i] = redactField(key, value).(string)
+		}
+		redacted[key] = copied
+	}
+	return redacted
+}
+
+// RedactHandler 在写入前脱敏日志消息和属性的 slog.Handler
+type RedactHandler struct {
+	handler slog.Handler
+}
+
+// NewRedactHandler 包装 handler，自动脱敏密钥、令牌和 api_key 等字段
+func NewRedactHandler(handler slog.Handler) *RedactHandler {
+	return &RedactHandler{handler: handler}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, RedactString(r.Message), r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &RedactHandler{handler: h.handler.WithAttrs(redacted)}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{handler: h.handler.WithGroup(name)}
+}
+
+// redactAttr 脱敏一个属性，分组属性递归处理
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, item := range group {
+			redacted[i] = redactAttr(item)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindString:
+		return slog.String(attr.Key, redactField(attr.Key, value.String()).(string))
+	case slog.KindAny:
+		return slog.Any(attr.Key, redactField(attr.Key, value.Any()))
+	}
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
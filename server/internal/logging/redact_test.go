@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestIsSensitive(t *testing.T) {
+	for key, want := range map[string]bool{
+		"api_key":       true,
+		"aes_key":       true,
+		"AES_IV_SEED":   true,
+		"Authorization": true,
+		"X-Admin-Token": true,
+		"token":         true,
+		"client_secret": true,
+		"token_id":      false,
+		"rai_hash":      false,
+		"request_id":    false,
+		"key_alias":     false,
+	} {
+		if got := IsSensitive(key); got != want {
+			t.Errorf("IsSensitive(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestRedactString(t *testing.T) {
+	for input, want := range map[string]string{
+		`{"model":"gpt-4","api_key":"sk-abc"}`:  `{"model":"gpt-4","api_key":"[REDACTED]"}`,
+		`{"aes_key": "c2VjcmV0\"x"}`:            `{"aes_key": "[REDACTED]"}`,
+		"token=abc.def&rai_hash=h1":             "token=[REDACTED]&rai_hash=h1",
+		"Authorization: Bearer abc.def-123":     "Authorization: Bearer [REDACTED]",
+		"upstream rejected sk-proj1234567890ab": "upstream rejected [REDACTED]",
+		"no secrets here":                       "no secrets here",
+	} {
+		if got := RedactString(input); got != want {
+			t.Errorf("RedactString(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	header := http.Header{"Authorization": {"Bearer sk-1234567890"}, "Content-Type": {"application/json"}}
+	redacted := RedactValue(header).(http.Header)
+	if redacted.Get("Authorization") != Redacted || redacted.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected redacted header: %v", redacted)
+	}
+	if header.Get("Authorization") != "Bearer sk-1234567890" {
+		t.Error("Expected the original header to be unchanged")
+	}
+
+	clients := map[string]interface{}{
+		"h1": map[string]interface{}{
+			"crypto": map[string]interface{}{"aes_key": "secret", "method": "aes"},
+			"empty":  map[string]string{"api_key": ""},
+		},
+	}
+	got, _ := json.Marshal(RedactValue(clients))
+	if strings.Contains(string(got), "secret") || !strings.Contains(string(got), `"method":"aes"`) {
+		t.Errorf("Unexpected redacted map: %s", got)
+	}
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, true, slog.LevelInfo).With("api_key", "sk-1234567890", "request_id", "r1")
+	log = FromContext(NewContext(context.Background(), log))
+
+	log.Debug("hidden")
+	log.Info("proxy failed for token=abc",
+		"headers", http.Header{"X-Api-Key": {"k"}},
+		"request_body", `{"api_key":"sk-abc"}`,
+		"aes_key", []byte("raw"),
+		slog.Group("client", "password", "p", "name", "n"),
+		"status", 502,
+	)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if strings.Contains(buf.String(), "sk-") || strings.Contains(buf.String(), "abc") {
+		t.Errorf("Expected secrets to be redacted, got %s", buf.String())
+	}
+	if entry["msg"] != "proxy failed for token=[REDACTED]" || entry["api_key"] != Redacted ||
+		entry["aes_key"] != Redacted || entry["request_id"] != "r1" || entry["status"] != float64(502) {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+	if client, _ := entry["client"].(map[string]interface{}); client["password"] != Redacted || client["name"] != "n" {
+		t.Errorf("Expected group attributes to be redacted, got %v", entry["client"])
+	}
+}
+
+func TestFromContextDefault(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("Expected the default logger without a request logger")
+	}
+}
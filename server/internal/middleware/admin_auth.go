@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"relayapi/server/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth 校验管理令牌，token 为空时不校验（配置校验保证此时管理接口只在本机监听上提供）
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		provided := c.GetHeader("X-Admin-Token")
+		if provided == "" {
+			provided = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			apierror.Abort(c, http.StatusUnauthorized, "invalid_admin_token", "Invalid or missing admin token")
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,14 +1,16 @@
 package middleware
 
 import (
-	"encoding/base64"
-	"log"
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/certs"
 	"relayapi/server/internal/config"
 	"relayapi/server/internal/crypto"
-	"relayapi/server/internal/models"
+	"relayapi/server/internal/logging"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,10 +23,52 @@ func splitStringByFirstSlash(input string) (string, string) {
 	return input[:index], input[index+1:]
 }
 
+// KeyGenerationKey 上下文中记录解密令牌所用密钥代数的键
+const KeyGenerationKey = "key_generation"
+
+// keyRingCache 按配置 hash 缓存密钥环，客户端配置注册表变化时整体失效
+type keyRingCache struct {
+	mu      sync.RWMutex
+	version uint64
+	rings   map[string]*crypto.KeyRing
+}
+
+// get 返回 version 版本配置下 hash 对应的密钥环，不存在时根据 clientCfg 创建
+func (k *keyRingCache) get(version uint64, hash string, clientCfg *config.ClientConfig) (*crypto.KeyRing, error) {
+	k.mu.RLock()
+	ring, ok := k.rings[hash]
+	current := k.version == version
+	k.mu.RUnlock()
+	if ok && current {
+		return ring, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if version < k.version {
+		// 请求读取的是旧版本的配置，只为本次请求创建，不写入缓存
+		return crypto.NewKeyRing(clientCfg)
+	}
+	if version > k.version || k.rings == nil {
+		// 配置已变化，丢弃旧的密钥环（密钥可能已被轮换或删除）
+		k.version = version
+		k.rings = make(map[string]*crypto.KeyRing)
+	}
+	if ring, ok := k.rings[hash]; ok {
+		return ring, nil
+	}
+	ring, err := crypto.NewKeyRing(clientCfg)
+	if err != nil {
+		return nil, err
+	}
+	k.rings[hash] = ring
+	return ring, nil
+}
+
 // TokenAuth 验证访问令牌的中间件
 func TokenAuth(cfg *config.Config) gin.HandlerFunc {
-	// 创建加密器映射
-	encryptors := make(map[string]crypto.Encryptor)
+	// 创建密钥环缓存
+	keyRings := &keyRingCache{}
 
 	return func(c *gin.Context) {
 		// 从 URL 参数中获取令牌
@@ -35,11 +79,13 @@ func TokenAuth(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		if encryptedToken == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Missing API token",
-				"message": "Please provide your API token as a URL parameter: ?token=your_token",
-			})
-			c.Abort()
+			// 没有令牌时使用已验证的客户端证书（mTLS）认证
+			if cert := certs.VerifiedClientCert(c.Request); cert != nil && cfg.Server.TLS.ClientAuth.Enabled {
+				clientCertAuth(c, &cfg.Server, cert)
+				return
+			}
+			apierror.Abort(c, http.StatusUnauthorized, "missing_token",
+				"Missing API token. Please provide your API token as a URL parameter: ?token=your_token")
 			return
 		}
 		encryptedToken, extPath := splitStringByFirstSlash(encryptedToken)
@@ -60,92 +106,52 @@ func TokenAuth(cfg *config.Config) gin.HandlerFunc {
 
 		if raiHash == "" {
 			// 如果没有指定 hash，使用第一个可用的配置
-			for hash := range cfg.Clients {
+			for hash := range cfg.ClientConfigs() {
 				raiHash = hash
 				break
 			}
 		}
-		log.Println("raiHash", raiHash)
-		log.Println("cfg.Clients", cfg.Clients)
-		clientCfg, ok := cfg.GetClientConfig(raiHash)
+		log := logging.FromContext(c.Request.Context())
+		clientCfg, version, ok := cfg.Clients.Lookup(raiHash)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Invalid configuration hash",
-				"message": "The provided configuration hash is not valid",
-			})
-			c.Abort()
+			log.Debug("Unknown client config", "rai_hash", raiHash)
+			apierror.Abort(c, http.StatusUnauthorized, "invalid_rai_hash",
+				"The provided configuration hash is not valid")
 			return
 		}
 
-		// 获取或创建加密器
-		encryptor, ok := encryptors[raiHash]
-		if !ok {
-			var err error
-			encryptor, err = crypto.NewEncryptor(&clientCfg)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "Encryptor initialization failed",
-					"message": err.Error(),
-				})
-				c.Abort()
-				return
-			}
-			encryptors[raiHash] = encryptor
-		}
-
-		// 清理令牌字符串
-		encryptedToken = strings.TrimSpace(encryptedToken)
-
-		// 添加回 base64 padding
-		if padding := len(encryptedToken) % 4; padding > 0 {
-			encryptedToken += strings.Repeat("=", 4-padding)
+		// 获取或创建密钥环
+		keyRing, err := keyRings.get(version, raiHash, &clientCfg)
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, "encryptor_error",
+				fmt.Sprintf("Encryptor initialization failed: %v", err))
+			return
 		}
 
 		// Base64 URL 安全解码
-		tokenBytes, err := base64.URLEncoding.DecodeString(encryptedToken)
+		tokenBytes, err := crypto.DecodeToken(encryptedToken)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":        "Invalid token format",
-				"message":      "Token must be base64url encoded",
-				"details":      err.Error(),
-				"token_length": len(encryptedToken),
-				"token_start":  encryptedToken[:10],
-			})
-			c.Abort()
+			apierror.Abort(c, http.StatusBadRequest, "invalid_token_format",
+				fmt.Sprintf("Token must be base64url encoded: %v", err))
 			return
 		}
 
-		// 解密令牌
-		decryptedBytes, err := encryptor.Decrypt(tokenBytes)
+		// 依次用当前密钥和未退役的旧密钥解密令牌
+		token, generation, err := keyRing.Open(tokenBytes)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Invalid token",
-				"message": "Failed to decrypt token",
-				"details": err.Error(),
-			})
-			c.Abort()
+			apierror.Abort(c, http.StatusUnauthorized, "invalid_token", fmt.Sprintf("Invalid token: %v", err))
 			return
 		}
-
-		// 反序列化令牌
-		token := &models.Token{}
-		if err := token.Deserialize(decryptedBytes); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Invalid token",
-				"message": "Failed to parse token data",
-				"details": err.Error(),
-			})
-			c.Abort()
-			return
+		c.Set(KeyGenerationKey, generation)
+		if generation != clientCfg.Crypto.Generation {
+			log.Info("Token decrypted with previous key generation", "token_id", token.ID,
+				"rai_hash", raiHash, "generation", generation, "current_generation", clientCfg.Crypto.Generation)
 		}
 
 		// 验证令牌有效性
 		if !token.IsValid() {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Token expired or exceeded usage limit",
-				"message": "Please obtain a new token",
-			})
-			c.Abort()
+			apierror.Abort(c, http.StatusUnauthorized, "token_expired",
+				"Token expired or exceeded usage limit. Please obtain a new token")
 			return
 		}
 
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/crypto"
+	"relayapi/server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mintToken 使用客户端配置的当前密钥签发令牌
+func mintToken(t *testing.T, clientCfg *config.ClientConfig, id string) string {
+	t.Helper()
+	encryptor, err := crypto.NewEncryptor(clientCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := crypto.EncryptToken(encryptor, &models.Token{
+		ID:         id,
+		APIKey:     "sk-test",
+		MaxCalls:   10,
+		ExpireTime: time.Now().Add(time.Hour),
+		CreatedAt:  time.Now(),
+		Provider:   "openai",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestTokenAuthKeyRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	original, err := config.DefaultClientConfig("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldHash := config.GenerateConfigHash(&original)
+	oldToken := mintToken(t, &original, "rotation-old")
+
+	rotated, err := config.RotateClientConfig(original, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ValidateClientConfig(&rotated); err != nil {
+		t.Fatalf("Rotated config is invalid: %v", err)
+	}
+	newToken := mintToken(t, &rotated, "rotation-new")
+
+	// 服务器只加载轮换后的配置
+	cfg := &config.Config{}
+	newHash := cfg.AddClientConfig(rotated)
+
+	router := gin.New()
+	router.GET("/relayapi/*path", TokenAuth(cfg), func(c *gin.Context) {
+		c.String(http.StatusOK, strconv.Itoa(c.GetInt(KeyGenerationKey)))
+	})
+
+	tests := []struct {
+		name       string
+		token      string
+		raiHash    string
+		generation string
+	}{
+		{"new key", newToken, newHash, "1"},
+		{"old token with old hash", oldToken, oldHash, "0"},
+		{"old token with new hash", oldToken, newHash, "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"token": {tt.token}, "rai_hash": {tt.raiHash}}
+			req := httptest.NewRequest(http.MethodGet, "/relayapi/models?"+query.Encode(), nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if w.Body.String() != tt.generation {
+				t.Errorf("Expected key generation %s, got %s", tt.generation, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTokenAuthRetiredKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	original, _ := config.DefaultClientConfig("", 0)
+	oldHash := config.GenerateConfigHash(&original)
+	oldToken := mintToken(t, &original, "retired-old")
+
+	// 旧密钥已经退役
+	rotated, err := config.RotateClientConfig(original, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	newHash := cfg.AddClientConfig(rotated)
+
+	router := gin.New()
+	router.GET("/relayapi/*path", TokenAuth(cfg), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, raiHash := range []string{oldHash, newHash} {
+		query := url.Values{"token": {oldToken}, "rai_hash": {raiHash}}
+		req := httptest.NewRequest(http.MethodGet, "/relayapi/models?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected retired key to be rejected, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestTokenAuthInvalidatesKeyRings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	original, _ := config.DefaultClientConfig("", 0)
+	oldToken := mintToken(t, &original, "invalidate-old")
+	rotated, err := config.RotateClientConfig(original, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	hash := cfg.Clients.SetFile("client.rai", rotated)
+
+	router := gin.New()
+	router.GET("/relayapi/*path", TokenAuth(cfg), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	request := func() int {
+		query := url.Values{"token": {oldToken}, "rai_hash": {hash}}
+		req := httptest.NewRequest(http.MethodGet, "/relayapi/models?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	// 修改文件使旧密钥提前退役，hash 不变但缓存的密钥环必须失效
+	rotated.Crypto.PreviousKeys[0].RetireAt = time.Now().Add(-time.Minute)
+	cfg.Clients.SetFile("client.rai", rotated)
+	if code := request(); code != http.StatusUnauthorized {
+		t.Errorf("Expected retired key to be rejected after reload, got %d", code)
+	}
+}
+
+func TestTokenAuthConcurrentReload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client, _ := config.DefaultClientConfig("", 0)
+	token := mintToken(t, &client, "concurrent")
+	cfg := &config.Config{}
+	hash := cfg.Clients.SetFile("client.rai", client)
+
+	router := gin.New()
+	router.GET("/relayapi/*path", TokenAuth(cfg), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				query := url.Values{"token": {token}, "rai_hash": {hash}}
+				req := httptest.NewRequest(http.MethodGet, "/relayapi/models?"+query.Encode(), nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK && w.Code != http.StatusUnauthorized {
+					t.Errorf("Unexpected status %d", w.Code)
+				}
+			}
+		}()
+	}
+
+	// 并发地重新加载、删除和添加配置
+	for i := 0; i < 50; i++ {
+		cfg.Clients.SetFile("client.rai", client)
+		cfg.Clients.RemoveFile("other.rai")
+		extra, _ := config.DefaultClientConfig("", 0)
+		cfg.Clients.SetFile("other.rai", extra)
+	}
+	wg.Wait()
+}
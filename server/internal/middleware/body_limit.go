@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit 按路由限制 API 请求体大小，超过限制返回 413
+func BodyLimit(cfg *config.Config, prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if c.Request.Body == nil || !strings.HasPrefix(path, prefix) {
+			c.Next()
+			return
+		}
+
+		limit := cfg.Server.MaxBodySize(strings.TrimPrefix(path, prefix))
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		// 已知长度的请求体直接拒绝，未知长度的在读取时限制
+		if c.Request.ContentLength > limit {
+			apierror.Abort(c, http.StatusRequestEntityTooLarge, "request_too_large",
+				fmt.Sprintf("Request body exceeds the limit of %d bytes", limit))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
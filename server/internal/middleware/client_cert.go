@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/certs"
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/logging"
+	"relayapi/server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientCertProfileKey 上下文中记录客户端证书匹配的令牌配置名称的键
+const ClientCertProfileKey = "client_cert_profile"
+
+// clientCertAuth 使用已验证的客户端证书认证，将证书匹配的令牌配置存入与 TokenAuth 相同的上下文
+func clientCertAuth(c *gin.Context, server *config.ServerConfig, cert *x509.Certificate) {
+	profile := matchClientCertProfile(server.TLS.ClientAuth.Profiles, cert)
+	if profile == nil {
+		logging.FromContext(c.Request.Context()).Warn("Client certificate not mapped to any token profile",
+			"subject", cert.Subject.String(), "identities", certs.Identities(cert))
+		apierror.Abort(c, http.StatusForbidden, "client_certificate_not_allowed",
+			fmt.Sprintf("Client certificate %s is not mapped to any token profile", cert.Subject))
+		return
+	}
+
+	token, err := clientCertToken(server, profile, cert)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "internal_error",
+			fmt.Sprintf("Invalid client certificate profile %s: %v", profile.Name, err))
+		return
+	}
+
+	// 验证令牌有效性，证书过期或超过配置的调用次数时拒绝
+	if !token.IsValid() {
+		apierror.Abort(c, http.StatusUnauthorized, "token_expired",
+			"Client certificate expired or token profile exceeded usage limit")
+		return
+	}
+
+	// 增加使用次数，同一配置的所有证书共享计数
+	token.IncrementUsage()
+
+	c.Set("token", token)
+	c.Set("api_key", token.APIKey)
+	c.Set(ClientCertProfileKey, profile.Name)
+	logging.FromContext(c.Request.Context()).Debug("Authenticated with client certificate",
+		"subject", cert.Subject.String(), "profile", profile.Name)
+
+	c.Next()
+}
+
+// matchClientCertProfile 返回第一个匹配证书身份的配置，没有时返回 nil
+func matchClientCertProfile(profiles []config.ClientCertProfile, cert *x509.Certificate) *config.ClientCertProfile {
+	identities := certs.Identities(cert)
+	for i := range profiles {
+		for _, identity := range profiles[i].Match {
+			if slices.Contains(identities, identity) {
+				return &profiles[i]
+			}
+		}
+	}
+	return nil
+}
+
+// clientCertToken 根据令牌配置创建令牌，有效期与证书相同
+func clientCertToken(server *config.ServerConfig, profile *config.ClientCertProfile, cert *x509.Certificate) (*models.Token, error) {
+	alias, ok := server.Admin.KeyAliases[profile.KeyAlias]
+	if !ok {
+		return nil, fmt.Errorf("unknown key alias: %s", profile.KeyAlias)
+	}
+	provider := profile.Provider
+	if provider == "" {
+		provider = alias.Provider
+	}
+
+	maxCalls := profile.MaxCalls
+	if maxCalls == 0 {
+		maxCalls = math.MaxInt
+	}
+
+	var extInfo string
+	if len(profile.ExtInfo) > 0 {
+		data, err := json.Marshal(profile.ExtInfo)
+		if err != nil {
+			return nil, err
+		}
+		extInfo = string(data)
+	}
+
+	return &models.Token{
+		ID:         "cert:" + profile.Name,
+		APIKey:     alias.APIKey,
+		MaxCalls:   maxCalls,
+		ExpireTime: cert.NotAfter,
+		CreatedAt:  cert.NotBefore,
+		Provider:   provider,
+		ExtInfo:    extInfo,
+	}, nil
+}
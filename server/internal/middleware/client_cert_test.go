@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenAuthClientCert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	cfg.Server.Admin.KeyAliases = map[string]config.KeyAlias{
+		"billing": {Provider: "openai", APIKey: "sk-billing"},
+	}
+	cfg.Server.TLS.ClientAuth.Enabled = true
+	cfg.Server.TLS.ClientAuth.Profiles = []config.ClientCertProfile{
+		{Name: "billing", Match: []string{"uri:spiffe://prod/billing"}, KeyAlias: "billing", MaxCalls: 2},
+		{Name: "reports", Match: []string{"cn:reports"}, KeyAlias: "billing", Provider: "dashscope",
+			ExtInfo: map[string]interface{}{"semantic_cache": true}},
+	}
+
+	router := gin.New()
+	router.GET("/relayapi/*path", TokenAuth(cfg), func(c *gin.Context) {
+		token := c.MustGet("token").(*models.Token)
+		c.JSON(http.StatusOK, gin.H{
+			"id":       token.ID,
+			"api_key":  token.APIKey,
+			"provider": token.Provider,
+			"ext_info": token.ExtInfo,
+			"profile":  c.GetString(ClientCertProfileKey),
+		})
+	})
+
+	spiffe, _ := url.Parse("spiffe://prod/billing")
+	billing := &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}, URIs: []*url.URL{spiffe},
+		NotAfter: time.Now().Add(time.Hour)}
+	reports := &x509.Certificate{Subject: pkix.Name{CommonName: "reports"}, NotAfter: time.Now().Add(time.Hour)}
+	unknown := &x509.Certificate{Subject: pkix.Name{CommonName: "unknown"}, NotAfter: time.Now().Add(time.Hour)}
+
+	request := func(cert *x509.Certificate) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/relayapi/models", nil)
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+				VerifiedChains:   [][]*x509.Certificate{{cert}},
+			}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(reports)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	want := `{"api_key":"sk-billing","ext_info":"{\"semantic_cache\":true}","id":"cert:reports","profile":"reports","provider":"dashscope"}`
+	if w.Body.String() != want {
+		t.Errorf("Expected %s, got %s", want, w.Body.String())
+	}
+
+	// 超过配置的调用次数后拒绝
+	for i, status := range []int{http.StatusOK, http.StatusOK, http.StatusUnauthorized} {
+		if w := request(billing); w.Code != status {
+			t.Errorf("Request %d: expected status %d, got %d: %s", i, status, w.Code, w.Body.String())
+		}
+	}
+	(&models.Token{ID: "cert:billing"}).ResetUsage()
+
+	if w := request(unknown); w.Code != http.StatusForbidden {
+		t.Errorf("Expected unmapped certificate to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := request(nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected request without token or certificate to be rejected, got %d", w.Code)
+	}
+
+	// 未启用客户端证书认证时忽略证书
+	cfg.Server.TLS.ClientAuth.Enabled = false
+	if w := request(reports); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected certificate to be ignored when client auth is disabled, got %d", w.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"relayapi/server/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Drainer 跟踪进行中的请求，关闭服务器时拒绝新请求并等待进行中的请求（包括流式响应）完成
+type Drainer struct {
+	mu       sync.Mutex
+	draining bool
+	active   int
+	idle     chan struct{} // 开始排空且没有进行中的请求时关闭
+}
+
+// NewDrainer 创建请求排空器
+func NewDrainer() *Drainer {
+	return &Drainer{idle: make(chan struct{})}
+}
+
+// Middleware 排空期间对新请求返回 503 并关闭连接，负载均衡器据此摘除实例
+func (d *Drainer) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !d.enter() {
+			c.Header("Connection", "close")
+			c.Header("Retry-After", "1")
+			apierror.Abort(c, http.StatusServiceUnavailable, "server_shutting_down", "Server is shutting down")
+			return
+		}
+		defer d.leave()
+		c.Next()
+	}
+}
+
+// enter 记录一个新请求，排空期间返回 false
+func (d *Drainer) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.active++
+	return true
+}
+
+// leave 请求结束，排空期间最后一个请求结束时通知 Wait
+func (d *Drainer) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active--
+	if d.draining && d.active == 0 {
+		close(d.idle)
+	}
+}
+
+// Start 开始排空，之后的新请求都会被拒绝
+func (d *Drainer) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return
+	}
+	d.draining = true
+	if d.active == 0 {
+		close(d.idle)
+	}
+}
+
+// Active 返回进行中的请求数
+func (d *Drainer) Active() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.active
+}
+
+// Wait 等待排空开始后所有进行中的请求结束，ctx 结束时返回其错误
+func (d *Drainer) Wait(ctx context.Context) error {
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
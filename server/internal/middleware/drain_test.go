@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDrainer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	drainer := NewDrainer()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(drainer.Middleware())
+	router.GET("/stream", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// 进行中的流式请求
+	streamDone := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+		streamDone <- w.Code
+	}()
+	<-started
+
+	drainer.Start()
+
+	// 排空期间拒绝新请求
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", w.Code)
+	}
+	if w.Header().Get("Connection") != "close" {
+		t.Error("Expected connection to be closed while draining")
+	}
+
+	// 进行中的请求未完成时 Wait 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := drainer.Wait(ctx); err == nil {
+		t.Error("Expected Wait to time out while a request is active")
+	}
+	if drainer.Active() != 1 {
+		t.Errorf("Expected 1 active request, got %d", drainer.Active())
+	}
+
+	close(release)
+	if code := <-streamDone; code != http.StatusOK {
+		t.Errorf("Expected in-flight request to complete, got %d", code)
+	}
+	if err := drainer.Wait(context.Background()); err != nil {
+		t.Errorf("Expected Wait to return after the last request, got %v", err)
+	}
+}
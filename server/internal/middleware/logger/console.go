@@ -1,13 +1,13 @@
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/hokaccha/go-prettyjson"
 )
 
 var (
@@ -31,20 +31,17 @@ func GetLogUpdateChan() chan struct{} {
 	return logUpdateChan
 }
 
-// ConsoleLogWriter 控制台日志写入器
+// ConsoleLogWriter 控制台日志写入器，每条请求和响应日志作为一条结构化日志输出，
+// 同时更新统计界面中的最近日志
 type ConsoleLogWriter struct {
-	formatter *prettyjson.Formatter
-	mu        sync.Mutex
+	log *slog.Logger
+	mu  sync.Mutex
 }
 
 // NewConsoleLogWriter 创建控制台日志写入器
-func NewConsoleLogWriter() *ConsoleLogWriter {
-	formatter := prettyjson.NewFormatter()
-	formatter.DisabledColor = true // 启用颜色输出
-	formatter.Indent = 2           // 设置缩进
-
+func NewConsoleLogWriter(log *slog.Logger) *ConsoleLogWriter {
 	return &ConsoleLogWriter{
-		formatter: formatter,
+		log: log,
 	}
 }
 
@@ -52,12 +49,7 @@ func (w *ConsoleLogWriter) Write(logs map[string]interface{}) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	output, err := w.formatter.Marshal(logs)
-	if err != nil {
-		return err
-	}
-
-	log.Println(string(output))
+	writeStructured(w.log, logs)
 
 	// 获取日志类型和时间
 	logType, _ := logs["type"].(string)
@@ -85,12 +77,12 @@ func (w *ConsoleLogWriter) Write(logs map[string]interface{}) error {
 
 	// 如果是响应日志
 	if logType == "response" {
-		status, _ := logs["status"].(float64)
-		latency, _ := logs["latency_ms"].(float64)
-		logLine = fmt.Sprintf("%s [%s] Status: %d, Latency: %.2fms",
+		status, _ := logs["status"].(int)
+		latency, _ := logs["latency_ms"].(int64)
+		logLine = fmt.Sprintf("%s [%s] Status: %d, Latency: %dms",
 			timeStr,
 			strings.ToUpper(logType),
-			int(status),
+			status,
 			latency)
 	}
 
@@ -112,6 +104,38 @@ func (w *ConsoleLogWriter) Write(logs map[string]interface{}) error {
 	return nil
 }
 
+// writeStructured 以结构化日志输出一条请求或响应日志，消息为日志类型，
+// 响应状态为 5xx 时为 ERROR 级别，4xx 时为 WARN 级别
+func writeStructured(l *slog.Logger, logs map[string]interface{}) {
+	logType, _ := logs["type"].(string)
+	level := slog.LevelInfo
+	if status, ok := logs["status"].(int); ok {
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+	}
+
+	keys := make([]string, 0, len(logs))
+	for key := range logs {
+		// 类型作为消息，时间由 slog 记录，请求 ID 放在最前面
+		if key != "type" && key != "time" && key != "request_id" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys)+1)
+	if requestID, ok := logs["request_id"]; ok {
+		attrs = append(attrs, slog.Any("request_id", requestID))
+	}
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, logs[key]))
+	}
+	l.LogAttrs(context.Background(), level, logType, attrs...)
+}
+
 func (w *ConsoleLogWriter) Close() error {
 	return nil
 }
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"relayapi/server/internal/logging"
+)
+
+func TestConsoleLogWriterStructured(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewConsoleLogWriter(logging.New(&buf, true, slog.LevelInfo))
+	writer.Write(map[string]interface{}{"request_id": "r1", "type": "request", "time": "2024-01-01T00:00:00Z", "path": "/v1/models",
+		"query": "token=abc", "headers": http.Header{"Authorization": {"Bearer sk-1234567890"}}})
+	writer.Write(map[string]interface{}{"request_id": "r1", "type": "response", "status": 404, "latency_ms": int64(3)})
+	writer.Write(map[string]interface{}{"request_id": "r2", "type": "response", "status": 502})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected one line per entry, got %q", buf.String())
+	}
+	for i, want := range []struct{ level, msg, requestID string }{
+		{"INFO", "request", "r1"},
+		{"WARN", "response", "r1"},
+		{"ERROR", "response", "r2"},
+	} {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("Line %d is not JSON: %v", i, err)
+		}
+		if entry["level"] != want.level || entry["msg"] != want.msg || entry["request_id"] != want.requestID {
+			t.Errorf("Line %d: expected %+v, got %v", i, want, entry)
+		}
+	}
+	// 令牌和 API Key 在输出前脱敏
+	if strings.Contains(buf.String(), "abc") || strings.Contains(buf.String(), "sk-") {
+		t.Errorf("Expected secrets to be redacted, got %s", lines[0])
+	}
+	if strings.Contains(buf.String(), "\033") {
+		t.Error("Expected no ANSI escape sequences")
+	}
+}
@@ -6,29 +6,51 @@ import (
 	"compress/zlib"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
+	"log/slog"
 	"mime"
+	"net/http"
 	"strings"
 	"time"
 
+	"relayapi/server/internal/apierror"
+	"relayapi/server/internal/cache"
 	"relayapi/server/internal/config"
+	"relayapi/server/internal/logging"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// maxLoggedResponseSize 日志中记录的响应体最大字节数，避免音频、图片等大响应占用内存
+const maxLoggedResponseSize = 1 << 20
+
 // bodyLogWriter 响应体写入器
 type bodyLogWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body      *bytes.Buffer
+	truncated bool
 }
 
-func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	if remaining := maxLoggedResponseSize - w.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			w.body.Write(b[:remaining])
+			w.truncated = true
+		} else {
+			w.body.Write(b)
+		}
+	} else if len(b) > 0 {
+		w.truncated = true
+	}
 	return w.ResponseWriter.Write(b)
 }
 
+// Unwrap 返回底层的 ResponseWriter，供 http.ResponseController 设置写超时
+func (w *bodyLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // decompressBody 根据Content-Encoding解压缩body
 func decompressBody(encoding string, body []byte) ([]byte, error) {
 	switch strings.ToLower(encoding) {
@@ -91,69 +113,41 @@ func getResponseBody(c *gin.Context, blw *bodyLogWriter) string {
 	return responseBody
 }
 
-// Middleware 创建日志中间件
-func Middleware(cfg *config.Config) gin.HandlerFunc {
-	var writers []LogWriter
-
-	// 根据配置创建日志写入器
-	if cfg.Server.Log.Console {
-		writers = append(writers, NewAsyncLogWriter(NewConsoleLogWriter(), 1000))
-	}
-
-	if cfg.Server.Log.Database.Enabled {
-		if dbWriter, err := NewDatabaseLogWriter(
-			cfg.Server.Log.Database.Type,
-			cfg.Server.Log.Database.ConnectionString,
-		); err == nil {
-			writers = append(writers, NewAsyncLogWriter(dbWriter, 1000))
-		} else {
-			fmt.Printf("Failed to create database log writer: %v\n", err)
-		}
-	}
-
-	if cfg.Server.Log.Web.Enabled {
-		writers = append(writers, NewAsyncLogWriter(NewWebLogWriter(cfg.Server.Log.Web.CallbackURL), 1000))
-	}
-
-	if cfg.Server.Log.Parquet.Enabled {
-		if parquetWriter, err := NewParquetLogWriter(cfg.Server.Log.Parquet.FilePath); err == nil {
-			writers = append(writers, NewAsyncLogWriter(parquetWriter, 1000))
-		} else {
-			fmt.Printf("Failed to create parquet log writer: %v\n", err)
-		}
-	}
+// Middleware 创建日志中间件，日志写入 writers 中的当前写入器，配置热重载时可以替换。
+// 带有 request_id 的 log 放入请求的 context，后续的中间件和处理器通过 logging.FromContext 获取
+func Middleware(cfg *config.Config, writers *WriterSet, log *slog.Logger) gin.HandlerFunc {
+	bodyThreshold := cfg.Server.BodyProcessThreshold()
 
 	return func(c *gin.Context) {
 		// 生成请求ID
 		requestID := uuid.New().String()
 		c.Set("request_id", requestID)
+		requestLogger := log.With("request_id", requestID)
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), requestLogger))
 
 		// 记录开始时间
 		startTime := time.Now()
 
-		// 读取请求体
+		// 只缓冲长度已知且不超过阈值的文本请求体，其余请求体（音频、图片、批量文件等）直接流式转发
 		var requestBody []byte
-		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
-
-			// 处理压缩的内容
-			if encoding := c.Request.Header.Get("Content-Encoding"); encoding != "" {
-				if decompressed, err := decompressBody(encoding, requestBody); err == nil {
-					requestBody = decompressed
+		bodySkipped := false
+		contentType := c.Request.Header.Get("Content-Type")
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			if isTextContent(contentType) && c.Request.ContentLength >= 0 && c.Request.ContentLength <= bodyThreshold {
+				requestBody, _ = io.ReadAll(c.Request.Body)
+				c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+
+				// 处理压缩的内容
+				if encoding := c.Request.Header.Get("Content-Encoding"); encoding != "" {
+					if decompressed, err := decompressBody(encoding, requestBody); err == nil {
+						requestBody = decompressed
+					}
 				}
+			} else {
+				bodySkipped = true
 			}
 		}
 
-		// 判断是否需要base64编码
-		var requestBodyStr string
-		contentType := c.Request.Header.Get("Content-Type")
-		if isTextContent(contentType) {
-			requestBodyStr = string(requestBody)
-		} else {
-			requestBodyStr = base64.StdEncoding.EncodeToString(requestBody)
-		}
-
 		// 记录请求日志
 		requestLog := map[string]interface{}{
 			"request_id":   requestID,
@@ -164,16 +158,16 @@ func Middleware(cfg *config.Config) gin.HandlerFunc {
 			"query":        c.Request.URL.RawQuery,
 			"client_ip":    c.ClientIP(),
 			"user_agent":   c.Request.UserAgent(),
-			"request_body": requestBodyStr,
+			"request_body": string(requestBody),
 			"headers":      c.Request.Header,
 		}
+		if bodySkipped {
+			requestLog["request_body_skipped"] = true
+			requestLog["request_body_size"] = c.Request.ContentLength
+		}
 
 		// 写入请求日志到所有写入器
-		for _, writer := range writers {
-			if err := writer.Write(requestLog); err != nil {
-				fmt.Printf("Failed to write request log: %v\n", err)
-			}
-		}
+		writeEntry(requestLog, writers, requestLogger)
 
 		// 包装响应写入器以捕获响应体
 		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
@@ -194,18 +188,47 @@ func Middleware(cfg *config.Config) gin.HandlerFunc {
 			"request_id":    requestID,
 			"type":          "response",
 			"time":          endTime.Format(time.RFC3339),
-			"status":        c.Writer.Status(),
+			"status":        apierror.Status(c),
 			"latency_ms":    latency.Milliseconds(),
 			"response_body": responseBody,
 			"headers":       c.Writer.Header(),
 			"errors":        c.Errors.Errors(),
 		}
+		if blw.truncated {
+			responseLog["response_body_truncated"] = true
+		}
+		if cacheStatus := c.GetString(cache.StatusKey); cacheStatus != "" {
+			responseLog["cache"] = cacheStatus
+		}
+		if c.GetBool("coalesced") {
+			responseLog["coalesced"] = true
+		}
+		// 解密令牌所用的密钥代数，用于跟踪密钥轮换
+		if generation, ok := c.Get("key_generation"); ok {
+			responseLog["key_generation"] = generation
+		}
+		// 使用客户端证书认证时匹配的令牌配置
+		if profile := c.GetString("client_cert_profile"); profile != "" {
+			responseLog["client_cert_profile"] = profile
+		}
+		// 客户端在响应完成前断开连接
+		if c.Request.Context().Err() != nil {
+			responseLog["canceled"] = true
+		}
 
 		// 写入响应日志到所有写入器
-		for _, writer := range writers {
-			if err := writer.Write(responseLog); err != nil {
-				fmt.Printf("Failed to write response log: %v\n", err)
-			}
+		writeEntry(responseLog, writers, requestLogger)
+	}
+}
+
+// writeEntry 脱敏一条请求或响应日志中的密钥和令牌（请求头、查询参数、请求体和响应体），
+// 再写入最近日志和所有写入器
+func writeEntry(entry map[string]interface{}, writers *WriterSet, log *slog.Logger) {
+	entry = logging.RedactValue(entry).(map[string]interface{})
+	recordRecentEntry(entry)
+	for _, writer := range writers.Load() {
+		if err := writer.Write(entry); err != nil {
+			log.Warn("Failed to write log", "type", entry["type"], "error", err)
 		}
 	}
 }
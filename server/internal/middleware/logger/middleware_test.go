@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryLogWriter 保存写入的日志
+type memoryLogWriter struct {
+	mu      sync.Mutex
+	entries []map[string]interface{}
+}
+
+func (w *memoryLogWriter) Write(log map[string]interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entries = append(w.entries, log)
+	return nil
+}
+
+func (w *memoryLogWriter) Close() error {
+	return nil
+}
+
+func TestMiddlewareRedactsEntries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memory := &memoryLogWriter{}
+	writers := &WriterSet{log: logging.Discard()}
+	writers.writers.Store(&[]LogWriter{memory})
+
+	router := gin.New()
+	router.Use(Middleware(&config.Config{}, writers, logging.Discard()))
+	router.POST("/relayapi-admin/tokens", func(c *gin.Context) {
+		c.Header("Set-Cookie", "session=s3cr3t")
+		c.JSON(http.StatusOK, gin.H{"token": "minted-token-value", "expire_time": "2030-01-01T00:00:00Z"})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/relayapi-admin/tokens?token=query-token&rai_hash=h1",
+		strings.NewReader(`{"api_key":"sk-upstream-key-123","provider":"openai"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer admin-token")
+	req.Header.Set("X-Api-Key", "header-key")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(memory.entries) != 2 {
+		t.Fatalf("Expected request and response entries, got %d", len(memory.entries))
+	}
+	request, response := memory.entries[0], memory.entries[1]
+	headers := request["headers"].(http.Header)
+	if headers.Get("Authorization") != logging.Redacted || headers.Get("X-Api-Key") != logging.Redacted {
+		t.Errorf("Expected request headers to be redacted, got %v", headers)
+	}
+	if request["query"] != "token="+logging.Redacted+"&rai_hash=h1" {
+		t.Errorf("Expected query token to be redacted, got %v", request["query"])
+	}
+	if body := request["request_body"].(string); strings.Contains(body, "sk-upstream") || !strings.Contains(body, `"provider":"openai"`) {
+		t.Errorf("Expected api_key in request body to be redacted, got %s", body)
+	}
+	if body := response["response_body"].(string); strings.Contains(body, "minted-token-value") {
+		t.Errorf("Expected minted token to be redacted, got %s", body)
+	}
+	if cookie := response["headers"].(http.Header).Get("Set-Cookie"); cookie != logging.Redacted {
+		t.Errorf("Expected Set-Cookie to be redacted, got %q", cookie)
+	}
+
+	// 管理接口查看的最近日志同样是脱敏后的副本
+	recent := RecentEntries(2)
+	if len(recent) != 2 || recent[0]["query"] != request["query"] {
+		t.Errorf("Expected recent entries to be redacted, got %v", recent)
+	}
+}
@@ -69,8 +69,8 @@ func (w *ParquetLogWriter) rotateIfNeeded() error {
 		w.fw.Close()
 	}
 
-	// 创建新文件
-	filename := filepath.Join(w.baseDir, fmt.Sprintf("logs_%s.parquet", currentDate))
+	// 创建新文件，已有的文件（重启或重新启用前写入的）不会被覆盖
+	filename := availableFile(w.baseDir, currentDate)
 	var err error
 	w.fw, err = local.NewLocalFileWriter(filename)
 	if err != nil {
@@ -91,6 +91,18 @@ func (w *ParquetLogWriter) rotateIfNeeded() error {
 	return nil
 }
 
+// availableFile 返回当天还不存在的日志文件名：logs_<日期>.parquet、logs_<日期>.1.parquet……
+// Parquet 文件写完后无法追加，创建已有的文件会截断它
+func availableFile(baseDir, date string) string {
+	filename := filepath.Join(baseDir, fmt.Sprintf("logs_%s.parquet", date))
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return filename
+		}
+		filename = filepath.Join(baseDir, fmt.Sprintf("logs_%s.%d.parquet", date, i))
+	}
+}
+
 func (w *ParquetLogWriter) Write(log map[string]interface{}) error {
 	if err := w.rotateIfNeeded(); err != nil {
 		return err
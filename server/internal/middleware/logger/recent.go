@@ -0,0 +1,34 @@
+package logger
+
+import "sync"
+
+// recentEntriesSize 保留的最近日志条数
+const recentEntriesSize = 200
+
+// 最近的请求和响应日志，供管理接口查看
+var (
+	recentEntries   []map[string]interface{}
+	recentEntriesMu sync.RWMutex
+)
+
+// recordRecentEntry 保存一条日志，超过容量时丢弃最早的日志
+func recordRecentEntry(entry map[string]interface{}) {
+	recentEntriesMu.Lock()
+	defer recentEntriesMu.Unlock()
+	recentEntries = append(recentEntries, entry)
+	if over := len(recentEntries) - recentEntriesSize; over > 0 {
+		recentEntries = append(recentEntries[:0:0], recentEntries[over:]...)
+	}
+}
+
+// RecentEntries 返回最近的 limit 条日志，按时间顺序排列
+func RecentEntries(limit int) []map[string]interface{} {
+	recentEntriesMu.RLock()
+	defer recentEntriesMu.RUnlock()
+	if limit <= 0 || limit > len(recentEntries) {
+		limit = len(recentEntries)
+	}
+	entries := make([]map[string]interface{}, limit)
+	copy(entries, recentEntries[len(recentEntries)-limit:])
+	return entries
+}
@@ -1,9 +1,18 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"relayapi/server/internal/config"
 )
 
+// errWriterClosed 写入已关闭的写入器（热重载替换后仍在处理的请求）
+var errWriterClosed = errors.New("log writer closed")
+
 // LogWriter 日志写入器接口
 type LogWriter interface {
 	Write(log map[string]interface{}) error
@@ -15,12 +24,16 @@ type AsyncLogWriter struct {
 	writer  LogWriter
 	logChan chan map[string]interface{}
 	done    chan struct{}
+	mu      sync.RWMutex // 防止关闭通道时仍有写入
+	closed  bool
+	log     *slog.Logger
 }
 
-// NewAsyncLogWriter 创建异步日志写入器
-func NewAsyncLogWriter(writer LogWriter, bufferSize int) *AsyncLogWriter {
+// NewAsyncLogWriter 创建异步日志写入器，写入失败的错误记录到 log
+func NewAsyncLogWriter(writer LogWriter, bufferSize int, log *slog.Logger) *AsyncLogWriter {
 	w := &AsyncLogWriter{
 		writer:  writer,
+		log:     log,
 		logChan: make(chan map[string]interface{}, bufferSize),
 		done:    make(chan struct{}),
 	}
@@ -29,15 +42,20 @@ func NewAsyncLogWriter(writer LogWriter, bufferSize int) *AsyncLogWriter {
 }
 
 func (w *AsyncLogWriter) processLogs() {
-	for log := range w.logChan {
-		if err := w.writer.Write(log); err != nil {
-			fmt.Printf("Failed to write log: %v\n", err)
+	for entry := range w.logChan {
+		if err := w.writer.Write(entry); err != nil {
+			w.log.Error("Failed to write log", "writer", fmt.Sprintf("%T", w.writer), "request_id", entry["request_id"], "error", err)
 		}
 	}
 	w.done <- struct{}{}
 }
 
 func (w *AsyncLogWriter) Write(log map[string]interface{}) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return errWriterClosed
+	}
 	select {
 	case w.logChan <- log:
 		return nil
@@ -47,16 +65,162 @@ func (w *AsyncLogWriter) Write(log map[string]interface{}) error {
 }
 
 func (w *AsyncLogWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
 	close(w.logChan)
+	w.mu.Unlock()
+
 	<-w.done
 	return w.writer.Close()
 }
 
 // CloseLogWriters 关闭所有日志写入器
-func CloseLogWriters(writers []LogWriter) {
+func CloseLogWriters(writers []LogWriter, log *slog.Logger) {
 	for _, writer := range writers {
 		if err := writer.Close(); err != nil {
-			fmt.Printf("Failed to close log writer: %v\n", err)
+			log.Error("Failed to close log writer", "error", err)
 		}
 	}
 }
+
+// writerSpec 配置启用的一个日志写入器，key 由写入器的配置组成
+type writerSpec struct {
+	key    string
+	create func() (LogWriter, error)
+}
+
+// writerSpecs 返回服务器配置启用的日志写入器
+func writerSpecs(cfg *config.ServerConfig, log *slog.Logger) []writerSpec {
+	var specs []writerSpec
+
+	if cfg.Log.Console {
+		specs = append(specs, writerSpec{"console", func() (LogWriter, error) {
+			return NewConsoleLogWriter(log), nil
+		}})
+	}
+
+	if cfg.Log.Database.Enabled {
+		specs = append(specs, writerSpec{"database\x00" + cfg.Log.Database.Type + "\x00" + cfg.Log.Database.ConnectionString, func() (LogWriter, error) {
+			dbWriter, err := NewDatabaseLogWriter(cfg.Log.Database.Type, cfg.Log.Database.ConnectionString)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create database log writer: %v", err)
+			}
+			return dbWriter, nil
+		}})
+	}
+
+	if cfg.Log.Web.Enabled {
+		specs = append(specs, writerSpec{"web\x00" + cfg.Log.Web.CallbackURL, func() (LogWriter, error) {
+			return NewWebLogWriter(cfg.Log.Web.CallbackURL), nil
+		}})
+	}
+
+	if cfg.Log.Parquet.Enabled {
+		specs = append(specs, writerSpec{"parquet\x00" + cfg.Log.Parquet.FilePath, func() (LogWriter, error) {
+			parquetWriter, err := NewParquetLogWriter(cfg.Log.Parquet.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create parquet log writer: %v", err)
+			}
+			return parquetWriter, nil
+		}})
+	}
+
+	return specs
+}
+
+// buildLogWriters 根据服务器配置创建日志写入器，配置与 current 中相同的写入器直接复用。
+// 返回全部写入器、按 key 索引的写入器、新创建的写入器和创建失败的错误
+func buildLogWriters(cfg *config.ServerConfig, log *slog.Logger, current map[string]LogWriter) ([]LogWriter, map[string]LogWriter, []LogWriter, []error) {
+	var writers, created []LogWriter
+	var errs []error
+	keyed := make(map[string]LogWriter)
+
+	for _, spec := range writerSpecs(cfg, log) {
+		if writer, ok := current[spec.key]; ok {
+			writers = append(writers, writer)
+			keyed[spec.key] = writer
+			continue
+		}
+		writer, err := spec.create()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		async := NewAsyncLogWriter(writer, 1000, log)
+		writers = append(writers, async)
+		keyed[spec.key] = async
+		created = append(created, async)
+	}
+
+	return writers, keyed, created, errs
+}
+
+// WriterSet 可在运行时原子替换的一组日志写入器
+type WriterSet struct {
+	mu      sync.Mutex // 串行化替换
+	writers atomic.Pointer[[]LogWriter]
+	keyed   map[string]LogWriter // 当前写入器按配置索引，热重载时保留配置不变的写入器
+	log     *slog.Logger
+}
+
+// NewWriterSet 根据服务器配置创建日志写入器集合，创建失败的写入器只记录错误并跳过。
+// 控制台日志和写入器自身的错误写入 log
+func NewWriterSet(cfg *config.ServerConfig, log *slog.Logger) *WriterSet {
+	writers, keyed, _, errs := buildLogWriters(cfg, log, nil)
+	for _, err := range errs {
+		log.Error("Failed to create log writer", "error", err)
+	}
+	s := &WriterSet{keyed: keyed, log: log}
+	s.writers.Store(&writers)
+	return s
+}
+
+// Load 返回当前的日志写入器
+func (s *WriterSet) Load() []LogWriter {
+	if writers := s.writers.Load(); writers != nil {
+		return *writers
+	}
+	return nil
+}
+
+// Reload 根据新的服务器配置替换当前集合。配置不变的写入器继续使用，不会重新打开
+// （重新创建 Parquet 写入器会截断正在写入的文件）；只关闭不再需要的写入器。
+// 任一写入器创建失败时关闭新创建的写入器，保留当前集合并返回错误
+func (s *WriterSet) Reload(cfg *config.ServerConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writers, keyed, created, errs := buildLogWriters(cfg, s.log, s.keyed)
+	if len(errs) > 0 {
+		CloseLogWriters(created, s.log)
+		return errors.Join(errs...)
+	}
+
+	var removed []LogWriter
+	for key, writer := range s.keyed {
+		if _, ok := keyed[key]; !ok {
+			removed = append(removed, writer)
+		}
+	}
+	s.writers.Store(&writers)
+	s.keyed = keyed
+
+	// 关闭旧的写入器会先写完缓冲中的日志
+	CloseLogWriters(removed, s.log)
+	return nil
+}
+
+// Close 关闭当前所有日志写入器
+func (s *WriterSet) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.writers.Swap(&[]LogWriter{})
+	s.keyed = nil
+	if old != nil {
+		CloseLogWriters(*old, s.log)
+	}
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/logging"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func TestWriterSetReload(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	cfg.Log.Console = true
+	writers := NewWriterSet(cfg, logging.Discard())
+	defer writers.Close()
+	old := writers.Load()
+	if len(old) != 1 {
+		t.Fatalf("Expected 1 writer, got %d", len(old))
+	}
+
+	// 创建失败时保留当前的写入器
+	invalid := &config.ServerConfig{}
+	invalid.Log.Console = true
+	invalid.Log.Database.Enabled = true
+	invalid.Log.Database.Type = "unknown"
+	if err := writers.Reload(invalid); err == nil {
+		t.Fatal("Expected reload with invalid writer to fail")
+	}
+	if current := writers.Load(); len(current) != 1 || current[0] != old[0] {
+		t.Error("Expected failed reload to keep the current writers")
+	}
+
+	// 替换后旧的写入器被关闭，仍在处理的请求写入旧写入器不会 panic
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				old[0].Write(map[string]interface{}{"type": "request"})
+			}
+		}()
+	}
+	if err := writers.Reload(&config.ServerConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if len(writers.Load()) != 0 {
+		t.Error("Expected console writer to be removed")
+	}
+	if err := old[0].Write(map[string]interface{}{}); err != errWriterClosed {
+		t.Errorf("Expected closed writer error, got %v", err)
+	}
+}
+
+// readParquetLogs 读取目录中所有 Parquet 日志文件的记录
+func readParquetLogs(t *testing.T, dir string) []LogRecord {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []LogRecord
+	for _, file := range files {
+		fr, err := local.NewLocalFileReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pr, err := reader.NewParquetReader(fr, new(LogRecord), 1)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		rows := make([]LogRecord, pr.GetNumRows())
+		if err := pr.Read(&rows); err != nil {
+			t.Fatalf("Failed to read rows of %s: %v", file, err)
+		}
+		pr.ReadStop()
+		fr.Close()
+		records = append(records, rows...)
+	}
+	return records
+}
+
+func TestWriterSetReloadParquet(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.ServerConfig{}
+	cfg.Log.Parquet.Enabled = true
+	cfg.Log.Parquet.FilePath = dir
+	writers := NewWriterSet(cfg, logging.Discard())
+	old := writers.Load()
+	old[0].Write(map[string]interface{}{"request_id": "r1", "type": "request"})
+
+	// 只修改限流配置时保留同一个写入器，不重新打开当天的文件
+	changed := &config.ServerConfig{}
+	changed.Log = cfg.Log
+	changed.RateLimit.RequestsPerSecond = 5
+	if err := writers.Reload(changed); err != nil {
+		t.Fatal(err)
+	}
+	if current := writers.Load(); len(current) != 1 || current[0] != old[0] {
+		t.Fatal("Expected unchanged parquet writer to be kept")
+	}
+	old[0].Write(map[string]interface{}{"request_id": "r2", "type": "request"})
+
+	// 停用后重新启用写入新的文件，不截断之前的文件
+	if err := writers.Reload(&config.ServerConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writers.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	writers.Load()[0].Write(map[string]interface{}{"request_id": "r3", "type": "request"})
+	writers.Close()
+
+	var ids []string
+	for _, record := range readParquetLogs(t, dir) {
+		ids = append(ids, record.RequestID)
+	}
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "r1,r2,r3" {
+		t.Errorf("Expected all records to survive reloads, got %v", ids)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"sync"
 
+	"relayapi/server/internal/apierror"
+
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
@@ -40,6 +42,26 @@ func (i *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
 	return limiter
 }
 
+// SetLimit 调整每个 IP 的速率和突发量，已有的限流器立即生效
+func (i *IPRateLimiter) SetLimit(r rate.Limit, b int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rate = r
+	i.burst = b
+	for _, limiter := range i.ips {
+		limiter.SetLimit(r)
+		limiter.SetBurst(b)
+	}
+}
+
+// Limit 返回当前每个 IP 的速率和突发量
+func (i *IPRateLimiter) Limit() (rate.Limit, int) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.rate, i.burst
+}
+
 func PathNormalizationMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
@@ -57,10 +79,7 @@ func RateLimit(globalLimiter *rate.Limiter, ipLimiter *IPRateLimiter) gin.Handle
 	return func(c *gin.Context) {
 		// 1. 检查全局限流
 		if !globalLimiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "too many requests",
-			})
-			c.Abort()
+			apierror.Abort(c, http.StatusTooManyRequests, "rate_limit_exceeded", "Too many requests")
 			return
 		}
 
@@ -68,10 +87,7 @@ func RateLimit(globalLimiter *rate.Limiter, ipLimiter *IPRateLimiter) gin.Handle
 		ip := c.ClientIP()
 		limiter := ipLimiter.GetLimiter(ip)
 		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "too many requests from your IP",
-			})
-			c.Abort()
+			apierror.Abort(c, http.StatusTooManyRequests, "rate_limit_exceeded", "Too many requests from your IP")
 			return
 		}
 
@@ -43,6 +43,17 @@ func (t *Token) IsValid() bool {
 	return true
 }
 
+// InvalidReason 返回令牌无效的原因（expired 或 usage_exceeded），有效时返回空字符串
+func (t *Token) InvalidReason() string {
+	if time.Now().After(t.ExpireTime) {
+		return "expired"
+	}
+	if t.GetRemainingCalls() <= 0 {
+		return "usage_exceeded"
+	}
+	return ""
+}
+
 // IncrementUsage 增加使用次数
 func (t *Token) IncrementUsage() {
 	usageMutex.Lock()
@@ -73,6 +84,55 @@ func (t *Token) ResetUsage() {
 	usageMutex.Unlock()
 }
 
+// AllUsage 返回所有令牌的使用次数副本
+func AllUsage() map[string]int {
+	usageMutex.RLock()
+	defer usageMutex.RUnlock()
+	usage := make(map[string]int, len(usageCounters))
+	for id, count := range usageCounters {
+		usage[id] = count
+	}
+	return usage
+}
+
+// TokenInfo 令牌的内容和当前进程中的使用情况，API Key 已脱敏
+type TokenInfo struct {
+	ID             string    `json:"id"`
+	APIKey         string    `json:"api_key"`
+	Provider       string    `json:"provider"`
+	MaxCalls       int       `json:"max_calls"`
+	UsedCalls      int       `json:"used_calls"`
+	RemainingCalls int       `json:"remaining_calls"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpireTime     time.Time `json:"expire_time"`
+	ExtInfo        string    `json:"ext_info,omitempty"`
+	Valid          bool      `json:"valid"`
+	Reason         string    `json:"reason,omitempty"` // expired 或 usage_exceeded
+	KeyGeneration  int       `json:"key_generation"`   // 解密令牌所用的密钥代数
+}
+
+// Info 返回令牌信息，不计入使用次数
+func (t *Token) Info() TokenInfo {
+	apiKey := "***"
+	if len(t.APIKey) > 12 {
+		apiKey = t.APIKey[:6] + "..." + t.APIKey[len(t.APIKey)-4:]
+	}
+	reason := t.InvalidReason()
+	return TokenInfo{
+		ID:             t.ID,
+		APIKey:         apiKey,
+		Provider:       t.Provider,
+		MaxCalls:       t.MaxCalls,
+		UsedCalls:      t.GetUsage(),
+		RemainingCalls: t.GetRemainingCalls(),
+		CreatedAt:      t.CreatedAt,
+		ExpireTime:     t.ExpireTime,
+		ExtInfo:        t.ExtInfo,
+		Valid:          reason == "",
+		Reason:         reason,
+	}
+}
+
 // Serialize 序列化令牌数据
 func (t *Token) Serialize() ([]byte, error) {
 	return json.Marshal(t)
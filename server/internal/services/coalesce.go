@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"relayapi/server/internal/cache"
+	"relayapi/server/internal/config"
+)
+
+// CoalescedKey 上下文中标记请求复用了其他请求响应的键
+const CoalescedKey = "coalesced"
+
+// Coalescer 合并相同的并发请求，同一时刻只向上游发送一次
+type Coalescer struct {
+	mu           sync.Mutex
+	calls        map[string]*coalescedCall
+	maxSize      int64
+	responseDeny []string // 配置的不转发给客户端的响应头，共享前删除
+}
+
+// coalescedCall 正在进行的上游请求
+type coalescedCall struct {
+	done  chan struct{}
+	entry *cache.Entry // 只有完整缓冲的 2xx 响应才会共享，否则为 nil
+}
+
+// NewCoalescer 根据服务器配置创建请求合并器，未启用时返回 nil
+func NewCoalescer(cfg *config.ServerConfig) *Coalescer {
+	if !cfg.Proxy.Coalesce.Enabled {
+		return nil
+	}
+	maxSize := cfg.Proxy.Coalesce.MaxResponseSize
+	if maxSize <= 0 {
+		maxSize = cfg.BodyProcessThreshold()
+	}
+	return &Coalescer{
+		calls:        make(map[string]*coalescedCall),
+		maxSize:      maxSize,
+		responseDeny: cfg.Proxy.Headers.ResponseDeny,
+	}
+}
+
+// Do 执行 fetch 或等待相同 key 的进行中请求。shared 为 true 表示响应来自其他请求；
+// 进行中的请求失败、上游返回非 2xx 响应或响应过大无法共享时，等待者会自行请求上游。
+// 合并的 key 不包含上游 API Key，一个调用方的密钥失效或额度耗尽不能影响其他调用方
+func (g *Coalescer) Do(ctx context.Context, key string, fetch func() (*http.Response, error)) (resp *http.Response, shared bool, err error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if call.entry != nil {
+			return call.entry.Response(), true, nil
+		}
+		resp, err := fetch()
+		return resp, false, err
+	}
+	call := &coalescedCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	resp, err = fetch()
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, false, nil
+	}
+
+	// 最多读取 maxSize + 1 字节，超过则把已读部分拼回响应体，不与其他请求共享
+	prefix, err := io.ReadAll(io.LimitReader(resp.Body, g.maxSize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, false, err
+	}
+	if int64(len(prefix)) > g.maxSize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(prefix), resp.Body), resp.Body}
+		return resp, false, nil
+	}
+	resp.Body.Close()
+
+	// 等待者只收到可以共享的响应头，发起请求的调用方收到完整的响应头
+	call.entry = &cache.Entry{StatusCode: resp.StatusCode, Header: cache.ShareableHeader(resp.Header, g.responseDeny), Body: prefix}
+	resp.Body = io.NopCloser(bytes.NewReader(prefix))
+	resp.ContentLength = int64(len(prefix))
+	return resp, false, nil
+}
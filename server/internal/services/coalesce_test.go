@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"relayapi/server/internal/config"
+)
+
+func TestCoalescerSharesResponse(t *testing.T) {
+	var upstreamCalls int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstreamCalls, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=leader")
+		w.Header().Set("X-Request-Id", "req_leader")
+		io.WriteString(w, `{"data":[{"embedding":[0.1]}]}`)
+	}))
+	defer ts.Close()
+
+	cfg := &config.ServerConfig{}
+	cfg.Proxy.Coalesce.Enabled = true
+	proxyService := NewProxyService(cfg)
+	coalescer := NewCoalescer(cfg)
+	fetch := func() (*http.Response, error) {
+		return proxyService.ProxyRequest(context.Background(), "POST", ts.URL, nil, []byte(`{"input":"hi"}`))
+	}
+
+	const waiters = 5
+	var wg sync.WaitGroup
+	var sharedCount int32
+	bodies := make([]string, waiters)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, shared, err := coalescer.Do(context.Background(), "key", fetch)
+			if err != nil {
+				t.Errorf("Do failed: %v", err)
+				return
+			}
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+				// 只属于领头请求的响应头不共享
+				if resp.Header.Get("Set-Cookie") != "" || resp.Header.Get("X-Request-Id") != "" {
+					t.Errorf("Expected per-response headers to be stripped, got %v", resp.Header)
+				}
+			} else if resp.Header.Get("Set-Cookie") == "" {
+				t.Error("Expected the leader to keep its own headers")
+			}
+			if resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Expected Content-Type to be shared, got %v", resp.Header)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			bodies[i] = string(body)
+		}(i)
+	}
+
+	// 等待所有请求进入合并器后再让上游返回
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&upstreamCalls); calls != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls)
+	}
+	if sharedCount != waiters-1 {
+		t.Errorf("Expected %d shared responses, got %d", waiters-1, sharedCount)
+	}
+	for _, body := range bodies {
+		if body != `{"data":[{"embedding":[0.1]}]}` {
+			t.Errorf("Unexpected body: %s", body)
+		}
+	}
+}
+
+func TestCoalescerLargeResponseNotShared(t *testing.T) {
+	payload := strings.Repeat("x", 64)
+	coalescer := &Coalescer{calls: make(map[string]*coalescedCall), maxSize: 16}
+
+	resp, shared, err := coalescer.Do(context.Background(), "key", func() (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(payload))}, nil
+	})
+	if err != nil || shared {
+		t.Fatalf("Unexpected result: shared=%v err=%v", shared, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != payload {
+		t.Errorf("Expected full body of %d bytes, got %d", len(payload), len(body))
+	}
+}
+
+func TestCoalescerErrorResponseNotShared(t *testing.T) {
+	coalescer := &Coalescer{calls: make(map[string]*coalescedCall), maxSize: 1024}
+	leaderStarted := make(chan struct{})
+	release := make(chan struct{})
+
+	// 领头请求的密钥失效，上游返回 401
+	var leaderStatus int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, _, err := coalescer.Do(context.Background(), "key", func() (*http.Response, error) {
+			close(leaderStarted)
+			<-release
+			return &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{},
+				Body: io.NopCloser(strings.NewReader(`{"error":"invalid api key"}`))}, nil
+		})
+		if err != nil {
+			t.Errorf("Leader failed: %v", err)
+			return
+		}
+		leaderStatus = resp.StatusCode
+	}()
+
+	<-leaderStarted
+	waiterDone := make(chan struct{})
+	var waiterResp *http.Response
+	var waiterShared bool
+	go func() {
+		defer close(waiterDone)
+		var err error
+		waiterResp, waiterShared, err = coalescer.Do(context.Background(), "key", func() (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+				Body: io.NopCloser(strings.NewReader(`{"ok":true}`))}, nil
+		})
+		if err != nil {
+			t.Errorf("Waiter failed: %v", err)
+		}
+	}()
+
+	// 等待第二个请求开始等待领头请求后再返回
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	<-done
+	<-waiterDone
+
+	if leaderStatus != http.StatusUnauthorized {
+		t.Errorf("Expected leader to get 401, got %d", leaderStatus)
+	}
+	if waiterResp == nil || waiterShared || waiterResp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected waiter to fetch its own 200, got shared=%v resp=%v", waiterShared, waiterResp)
+	}
+	if body, _ := io.ReadAll(waiterResp.Body); string(body) != `{"ok":true}` {
+		t.Errorf("Unexpected waiter body: %s", body)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"relayapi/server/internal/cache"
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/utils"
+)
+
+// EmbeddingClient 通过代理服务调用提供商的 embeddings 接口
+type EmbeddingClient struct {
+	proxy    *ProxyService
+	provider string
+	model    string
+	apiKey   string
+}
+
+// NewEmbeddingClient 根据语义缓存配置创建嵌入客户端
+func NewEmbeddingClient(proxy *ProxyService, cfg *config.ServerConfig) *EmbeddingClient {
+	semantic := cfg.Cache.Semantic
+	model := semantic.Model
+	if model == "" {
+		model = cache.DefaultEmbeddingModel
+	}
+	return &EmbeddingClient{
+		proxy:    proxy,
+		provider: semantic.Provider,
+		model:    model,
+		apiKey:   semantic.APIKey,
+	}
+}
+
+// noEmbeddingProviders 没有 OpenAI 兼容 /embeddings 接口的提供商
+var noEmbeddingProviders = map[string]bool{
+	"anthropic":        true,
+	"anthropic-openai": true,
+	"googleai":         true,
+	"googleai-openai":  true,
+	"googleaibeta":     true,
+}
+
+// Supports 判断能否为该令牌提供商计算嵌入向量，未配置提供商时取决于令牌的提供商
+func (e *EmbeddingClient) Supports(provider string) bool {
+	if e.provider != "" {
+		provider = e.provider
+	}
+	return !noEmbeddingProviders[provider]
+}
+
+// Embed 计算文本的嵌入向量，未配置提供商或 API Key 时使用令牌的提供商和 API Key
+func (e *EmbeddingClient) Embed(ctx context.Context, provider, apiKey, text string) ([]float32, error) {
+	if e.provider != "" {
+		provider = e.provider
+	}
+	if e.apiKey != "" {
+		apiKey = e.apiKey
+	}
+
+	body, err := json.Marshal(map[string]string{"model": e.model, "input": text})
+	if err != nil {
+		return nil, err
+	}
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Authorization", "Bearer "+apiKey)
+
+	targetURL := fmt.Sprintf("%s/embeddings", utils.GetProviderBaseURL(provider))
+	resp, err := e.proxy.ProxyRequest(ctx, http.MethodPost, targetURL, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := e.proxy.ReadResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings request failed with status %d: %s", resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %v", err)
+	}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embeddings response contains no vector")
+	}
+	return result.Data[0].Embedding, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"relayapi/server/internal/config"
+)
+
+func TestEmbeddingClientEmbed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" || r.Header.Get("Authorization") != "Bearer sk-embed" {
+			t.Errorf("Unexpected request: %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] != "text-embedding-3-small" || req["input"] != "user: hi\n" {
+			t.Errorf("Unexpected embeddings request: %v", req)
+		}
+		w.Write([]byte(`{"data":[{"embedding":[0.1,0.2,0.3]}]}`))
+	}))
+	defer ts.Close()
+
+	cfg := &config.ServerConfig{}
+	cfg.Cache.Semantic.APIKey = "sk-embed"
+	client := NewEmbeddingClient(NewProxyService(cfg), cfg)
+
+	// 未配置提供商时使用令牌的提供商（这里直接使用测试服务器地址）
+	vector, err := client.Embed(context.Background(), ts.URL, "sk-token", "user: hi\n")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vector) != 3 || vector[2] != 0.3 {
+		t.Errorf("Unexpected vector: %v", vector)
+	}
+}
+
+func TestEmbeddingClientSupports(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	client := NewEmbeddingClient(NewProxyService(cfg), cfg)
+	if !client.Supports("openai") || client.Supports("anthropic-openai") || client.Supports("googleai-openai") {
+		t.Error("Expected only providers with an embeddings endpoint to be supported")
+	}
+
+	// 配置了嵌入提供商时与令牌的提供商无关
+	cfg.Cache.Semantic.Provider = "openai"
+	client = NewEmbeddingClient(NewProxyService(cfg), cfg)
+	if !client.Supports("anthropic-openai") {
+		t.Error("Expected the configured embeddings provider to be used")
+	}
+	cfg.Cache.Semantic.Provider = "anthropic"
+	client = NewEmbeddingClient(NewProxyService(cfg), cfg)
+	if client.Supports("openai") {
+		t.Error("Expected a configured provider without embeddings to be unsupported")
+	}
+}
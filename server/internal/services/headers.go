@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"relayapi/server/internal/config"
+)
+
+// hopByHopHeaders RFC 7230 定义的逐跳头，不能被代理转发
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// defaultDeniedHeaders 默认不转发给提供商的请求头
+var defaultDeniedHeaders = []string{
+	"Host",
+	"Cookie",
+	"Authorization",
+	"X-Api-Key",
+	"X-Goog-Api-Key",
+	"Content-Length",
+	"Accept-Encoding",
+	"X-Forwarded-For",
+	"X-Real-Ip",
+	"Forwarded",
+	"Via",
+}
+
+// HeaderPolicy 请求头和响应头的转发策略
+type HeaderPolicy struct {
+	forward       map[string]bool
+	deny          map[string]bool
+	responseDeny  map[string]bool
+	xForwardedFor bool
+	via           bool
+}
+
+// NewHeaderPolicy 根据服务器配置创建转发策略
+func NewHeaderPolicy(cfg *config.ServerConfig) *HeaderPolicy {
+	headers := cfg.Proxy.Headers
+	return &HeaderPolicy{
+		forward:       headerSet(headers.Forward),
+		deny:          headerSet(append(append([]string{}, defaultDeniedHeaders...), headers.Deny...)),
+		responseDeny:  headerSet(headers.ResponseDeny),
+		xForwardedFor: headers.XForwardedFor,
+		via:           headers.Via,
+	}
+}
+
+// OutboundHeaders 根据客户端请求头生成转发给提供商的请求头
+func (p *HeaderPolicy) OutboundHeaders(r *http.Request, clientIP string) http.Header {
+	out := r.Header.Clone()
+	if out == nil {
+		out = make(http.Header)
+	}
+	removeHopByHopHeaders(out)
+
+	for key := range out {
+		if p.deny[key] || (len(p.forward) > 0 && !p.forward[key]) {
+			out.Del(key)
+		}
+	}
+
+	// 追加客户端地址，保留已有的代理链
+	if p.xForwardedFor && clientIP != "" {
+		if prior := r.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		out.Set("X-Forwarded-For", clientIP)
+	}
+	if p.via {
+		via := fmt.Sprintf("%d.%d relayapi", r.ProtoMajor, r.ProtoMinor)
+		if prior := r.Header.Values("Via"); len(prior) > 0 {
+			via = strings.Join(prior, ", ") + ", " + via
+		}
+		out.Set("Via", via)
+	}
+	return out
+}
+
+// CopyResponseHeaders 将提供商的响应头复制给客户端，保留多值头
+func (p *HeaderPolicy) CopyResponseHeaders(dst, src http.Header) {
+	header := src.Clone()
+	removeHopByHopHeaders(header)
+	for key, values := range header {
+		if p.responseDeny[key] {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// removeHopByHopHeaders 删除逐跳头以及 Connection 中声明的头
+func removeHopByHopHeaders(h http.Header) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		h.Del(name)
+	}
+}
+
+// headerSet 将头名称列表转换为规范化的集合
+func headerSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+	}
+	return set
+}
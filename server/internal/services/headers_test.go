@@ -0,0 +1,87 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"relayapi/server/internal/config"
+)
+
+func TestHeaderPolicyOutboundHeaders(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	cfg.Proxy.Headers.Deny = []string{"x-internal"}
+	cfg.Proxy.Headers.XForwardedFor = true
+	cfg.Proxy.Headers.Via = true
+	policy := NewHeaderPolicy(cfg)
+
+	req := httptest.NewRequest("POST", "/relayapi/chat/completions", nil)
+	req.Header.Set("Host", "relay.example.com")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("Connection", "keep-alive, X-Hop")
+	req.Header.Set("X-Hop", "1")
+	req.Header.Set("X-Internal", "1")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept", "text/event-stream")
+
+	out := policy.OutboundHeaders(req, "192.168.1.2")
+
+	for _, name := range []string{"Host", "Cookie", "Connection", "X-Hop", "X-Internal", "Accept-Encoding"} {
+		if out.Get(name) != "" {
+			t.Errorf("Header %s should not be forwarded", name)
+		}
+	}
+	if values := out.Values("Accept"); len(values) != 2 {
+		t.Errorf("Expected 2 Accept values, got %v", values)
+	}
+	if got := out.Get("X-Forwarded-For"); got != "10.0.0.1, 192.168.1.2" {
+		t.Errorf("Unexpected X-Forwarded-For: %s", got)
+	}
+	if got := out.Get("Via"); got != "1.1 relayapi" {
+		t.Errorf("Unexpected Via: %s", got)
+	}
+}
+
+func TestHeaderPolicyForwardList(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	cfg.Proxy.Headers.Forward = []string{"Content-Type", "OpenAI-Beta"}
+	policy := NewHeaderPolicy(cfg)
+
+	req := httptest.NewRequest("POST", "/relayapi/chat/completions", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OpenAI-Beta", "assistants=v2")
+	req.Header.Set("User-Agent", "browser")
+
+	out := policy.OutboundHeaders(req, "192.168.1.2")
+	if out.Get("Content-Type") == "" || out.Get("OpenAI-Beta") == "" {
+		t.Error("Allowed headers should be forwarded")
+	}
+	if out.Get("User-Agent") != "" || out.Get("X-Forwarded-For") != "" {
+		t.Error("Headers outside the forward list should be dropped")
+	}
+}
+
+func TestHeaderPolicyCopyResponseHeaders(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	cfg.Proxy.Headers.ResponseDeny = []string{"Set-Cookie"}
+	policy := NewHeaderPolicy(cfg)
+
+	src := http.Header{}
+	src.Set("Transfer-Encoding", "chunked")
+	src.Set("Keep-Alive", "timeout=5")
+	src.Set("Set-Cookie", "__cf_bm=1")
+	src.Add("X-Ratelimit", "a")
+	src.Add("X-Ratelimit", "b")
+
+	dst := http.Header{}
+	policy.CopyResponseHeaders(dst, src)
+
+	if dst.Get("Transfer-Encoding") != "" || dst.Get("Keep-Alive") != "" || dst.Get("Set-Cookie") != "" {
+		t.Errorf("Unexpected headers copied: %v", dst)
+	}
+	if values := dst.Values("X-Ratelimit"); len(values) != 2 {
+		t.Errorf("Expected 2 X-Ratelimit values, got %v", values)
+	}
+}
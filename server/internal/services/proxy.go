@@ -3,44 +3,112 @@ package services
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"relayapi/server/internal/config"
 
 	"github.com/gin-gonic/gin"
 )
 
+// StatusClientClosedRequest 客户端在响应完成前断开连接时记录的状态码（沿用 nginx 的 499）
+const StatusClientClosedRequest = 499
+
+// StreamConverter 转换上游 SSE 事件的 data 内容
+type StreamConverter interface {
+	Convert(data []byte) ([][]byte, error)
+	Finish() ([][]byte, error)
+}
+
 // ProxyService 处理 API 代理请求
 type ProxyService struct {
-	client *http.Client
+	client   *http.Client
+	mu       sync.RWMutex // 保护 timeouts，配置热重载时替换
+	timeouts Timeouts
 }
 
 // NewProxyService 创建新的代理服务
-func NewProxyService() *ProxyService {
+func NewProxyService(cfg *config.ServerConfig) *ProxyService {
 	return &ProxyService{
-		client: &http.Client{},
+		client:   &http.Client{},
+		timeouts: NewTimeouts(cfg),
 	}
 }
 
-// ProxyRequest 转发 API 请求
-func (s *ProxyService) ProxyRequest(method, url string, headers map[string]string, body []byte) (*http.Response, error) {
+// Timeouts 返回当前的上游超时设置
+func (s *ProxyService) Timeouts() Timeouts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.timeouts
+}
+
+// SetTimeouts 替换上游超时设置，只对之后开始的请求生效
+func (s *ProxyService) SetTimeouts(timeouts Timeouts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeouts = timeouts
+}
+
+// ProxyRequest 转发 API 请求，ctx 取消时上游请求随之中止
+func (s *ProxyService) ProxyRequest(ctx context.Context, method, url string, headers http.Header, body []byte) (*http.Response, error) {
+	return s.ProxyStreamRequest(ctx, method, url, headers, bytes.NewReader(body), int64(len(body)))
+}
+
+// ProxyStreamRequest 以流的方式转发请求体，contentLength 为 -1 时使用分块传输
+func (s *ProxyService) ProxyStreamRequest(ctx context.Context, method, url string, headers http.Header, body io.Reader, contentLength int64) (*http.Response, error) {
+	timeouts := s.Timeouts()
+
+	// 上游请求绑定客户端请求的上下文，超时时以具体原因取消
+	upstreamCtx, cancel := context.WithCancelCause(ctx)
+
 	// 创建请求
-	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(upstreamCtx, method, url, body)
 	if err != nil {
+		cancel(nil)
 		return nil, err
 	}
+	req.ContentLength = contentLength
+	if contentLength == 0 {
+		req.Body = http.NoBody
+	}
+
+	// 设置请求头，保留多值头
+	for key, values := range headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
 
-	// 设置请求头
-	for key, value := range headers {
-		req.Header.Set(key, value)
+	// 等待响应头的时间受首字节超时限制
+	var firstByteTimer *time.Timer
+	if timeouts.FirstByte > 0 {
+		firstByteTimer = time.AfterFunc(timeouts.FirstByte, func() { cancel(ErrFirstByteTimeout) })
 	}
 
 	// 发送请求
 	resp, err := s.client.Do(req)
+	if firstByteTimer != nil {
+		firstByteTimer.Stop()
+	}
 	if err != nil {
+		if cause := context.Cause(upstreamCtx); errors.Is(cause, ErrFirstByteTimeout) {
+			err = cause
+		}
+		cancel(nil)
 		return nil, err
 	}
 
+	// 只有流式响应受总时长限制，空闲超时对所有响应体生效
+	var stream time.Duration
+	if isStreamingResponse(resp) {
+		stream = timeouts.Stream
+	}
+	resp.Body = newTimeoutBody(upstreamCtx, cancel, resp.Body, timeouts.Idle, stream)
+
 	return resp, nil
 }
 
@@ -50,43 +118,121 @@ func (s *ProxyService) ReadResponse(resp *http.Response) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-// HandleStreamResponse 处理流式响应
-func (s *ProxyService) HandleStreamResponse(c *gin.Context, resp *http.Response) error {
+// copyFlushInterval 已知长度响应体的刷新间隔
+const copyFlushInterval = 100 * time.Millisecond
+
+// CopyResponse 原样转发响应体，适用于二进制、分块传输和 NDJSON 等非 SSE 响应
+func (s *ProxyService) CopyResponse(ctx context.Context, c *gin.Context, resp *http.Response) error {
+	defer resp.Body.Close()
+	c.Status(resp.StatusCode)
+
+	// 大文件和分块响应可能超过全局写超时，由 Idle/Stream 超时控制
+	ClearWriteDeadline(c)
+
+	// 长度未知（分块传输、NDJSON）时每次写入后立即刷新，否则按固定间隔刷新
+	flushEachWrite := resp.ContentLength < 0
+	lastFlush := time.Now()
+	buf := make([]byte, 32*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if flushEachWrite || time.Since(lastFlush) >= copyFlushInterval {
+				c.Writer.Flush()
+				lastFlush = time.Now()
+			}
+		}
+		if err == io.EOF {
+			c.Writer.Flush()
+			return nil
+		}
+		if err != nil {
+			return contextError(ctx, err)
+		}
+	}
+}
+
+// HandleStreamResponse 处理流式响应，converter 不为空时逐条转换 SSE 事件。
+// 客户端断开时 ctx 被取消，上游连接随之关闭并返回 ctx.Err()
+func (s *ProxyService) HandleStreamResponse(ctx context.Context, c *gin.Context, resp *http.Response, converter StreamConverter) error {
 	// 设置响应头
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Transfer-Encoding", "chunked")
 
 	// 获取原始响应的 Content-Type
 	contentType := resp.Header.Get("Content-Type")
 	isEventStream := strings.Contains(contentType, "text/event-stream")
 
-	// 创建一个 reader
-	reader := bufio.NewReader(resp.Body)
+	// 在后台逐行读取上游数据，主循环负责写出数据和保活注释
+	done := make(chan struct{})
+	defer close(done)
 	defer resp.Body.Close()
+	lines := readLines(resp.Body, done)
+
+	timeouts := s.Timeouts()
+	var keepalive *time.Ticker
+	var keepaliveC <-chan time.Time
+	if timeouts.Keepalive > 0 {
+		keepalive = time.NewTicker(timeouts.Keepalive)
+		defer keepalive.Stop()
+		keepaliveC = keepalive.C
+	}
+
+	// 流式响应由 Stream 超时控制时长，不受全局写超时限制
+	ClearWriteDeadline(c)
 
 	// 刷新写入器以确保头信息被发送
 	c.Writer.Flush()
 
 	for {
-		// 读取一行数据
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			if err == io.EOF {
+		var line streamLine
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-keepaliveC:
+			// 上游空闲时发送 SSE 注释，防止中间代理关闭连接
+			if _, err := io.WriteString(c.Writer, ": keepalive\n\n"); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			continue
+		case line = <-lines:
+		}
+
+		if line.err != nil {
+			if line.err == io.EOF {
+				if converter != nil {
+					return finishConverter(c, converter)
+				}
 				return nil
 			}
-			return err
+			return contextError(ctx, line.err)
+		}
+		if keepalive != nil {
+			keepalive.Reset(timeouts.Keepalive)
+		}
+
+		// 需要转换格式时，只处理 data 行
+		if converter != nil {
+			if err := writeConvertedLine(c, converter, line.data); err != nil {
+				return err
+			}
+			continue
 		}
 
 		// 如果不是 SSE 格式，转换为 SSE 格式
-		if !isEventStream && len(line) > 0 {
-			line = []byte("data: " + string(line) + "\n\n")
+		data := line.data
+		if !isEventStream && len(data) > 0 {
+			data = []byte("data: " + string(data) + "\n\n")
 		}
 
 		// 写入数据
-		_, err = c.Writer.Write(line)
-		if err != nil {
+		if _, err := c.Writer.Write(data); err != nil {
 			return err
 		}
 
@@ -94,3 +240,77 @@ func (s *ProxyService) HandleStreamResponse(c *gin.Context, resp *http.Response)
 		c.Writer.Flush()
 	}
 }
+
+// streamLine 从上游读取的一行数据
+type streamLine struct {
+	data []byte
+	err  error
+}
+
+// readLines 在后台逐行读取 r，读取出错或 done 关闭后退出
+func readLines(r io.Reader, done <-chan struct{}) <-chan streamLine {
+	lines := make(chan streamLine)
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			data, err := reader.ReadBytes('\n')
+			select {
+			case lines <- streamLine{data: data, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+// contextError 上下文已取消时返回取消原因，便于调用方区分客户端断开和上游错误
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// writeConvertedLine 转换一行 SSE 数据并写出
+func writeConvertedLine(c *gin.Context, converter StreamConverter, line []byte) error {
+	trimmed := bytes.TrimSpace(line)
+	if !bytes.HasPrefix(trimmed, []byte("data:")) {
+		return nil
+	}
+	data := bytes.TrimSpace(bytes.TrimPrefix(trimmed, []byte("data:")))
+	if len(data) == 0 {
+		return nil
+	}
+
+	payloads, err := converter.Convert(data)
+	if err != nil {
+		return err
+	}
+	return writePayloads(c, payloads)
+}
+
+// finishConverter 在上游流结束时写出转换器剩余的数据
+func finishConverter(c *gin.Context, converter StreamConverter) error {
+	payloads, err := converter.Finish()
+	if err != nil {
+		return err
+	}
+	return writePayloads(c, payloads)
+}
+
+// writePayloads 以 SSE data 行的形式写出负载
+func writePayloads(c *gin.Context, payloads [][]byte) error {
+	for _, payload := range payloads {
+		if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", payload); err != nil {
+			return err
+		}
+	}
+	if len(payloads) > 0 {
+		c.Writer.Flush()
+	}
+	return nil
+}
@@ -1,9 +1,17 @@
 package services
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"relayapi/server/internal/config"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestProxyRequest(t *testing.T) {
@@ -24,16 +32,16 @@ func TestProxyRequest(t *testing.T) {
 	defer ts.Close()
 
 	// 创建代理服务
-	proxyService := NewProxyService()
+	proxyService := NewProxyService(&config.ServerConfig{})
 
 	// 测试请求
-	headers := map[string]string{
-		"Content-Type":   "application/json",
-		"X-Test-Header": "test-value",
+	headers := http.Header{
+		"Content-Type":  {"application/json"},
+		"X-Test-Header": {"test-value"},
 	}
 	body := []byte(`{"test":"data"}`)
 
-	resp, err := proxyService.ProxyRequest("POST", ts.URL, headers, body)
+	resp, err := proxyService.ProxyRequest(context.Background(), "POST", ts.URL, headers, body)
 	if err != nil {
 		t.Fatalf("ProxyRequest failed: %v", err)
 	}
@@ -56,11 +64,105 @@ func TestProxyRequest(t *testing.T) {
 }
 
 func TestProxyRequestError(t *testing.T) {
-	proxyService := NewProxyService()
+	proxyService := NewProxyService(&config.ServerConfig{})
 
 	// 测试无效 URL
-	_, err := proxyService.ProxyRequest("GET", "invalid-url", nil, nil)
+	_, err := proxyService.ProxyRequest(context.Background(), "GET", "invalid-url", nil, nil)
 	if err == nil {
 		t.Error("Expected error for invalid URL")
 	}
-} 
\ No newline at end of file
+}
+func TestProxyStreamRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 长度未知的请求体应使用分块传输
+		if r.ContentLength != -1 {
+			t.Errorf("Expected unknown content length, got %d", r.ContentLength)
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	proxyService := NewProxyService(&config.ServerConfig{})
+	payload := strings.Repeat("audio-bytes", 1024)
+	headers := http.Header{"Content-Type": {"application/octet-stream"}}
+
+	resp, err := proxyService.ProxyStreamRequest(context.Background(), "POST", ts.URL, headers, strings.NewReader(payload), -1)
+	if err != nil {
+		t.Fatalf("ProxyStreamRequest failed: %v", err)
+	}
+	respBody, err := proxyService.ReadResponse(resp)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	if string(respBody) != payload {
+		t.Errorf("Expected echoed body of %d bytes, got %d", len(payload), len(respBody))
+	}
+}
+
+func TestCopyResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	payload := strings.Repeat("\x00\xffpcm", 20000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.WriteHeader(http.StatusCreated)
+		w.(http.Flusher).Flush()
+		io.WriteString(w, payload)
+	}))
+	defer ts.Close()
+
+	proxyService := NewProxyService(&config.ServerConfig{})
+	resp, err := proxyService.ProxyRequest(context.Background(), "GET", ts.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("ProxyRequest failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if err := proxyService.CopyResponse(context.Background(), c, resp); err != nil {
+		t.Fatalf("CopyResponse failed: %v", err)
+	}
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if w.Body.String() != payload {
+		t.Errorf("Expected %d bytes copied unchanged, got %d", len(payload), w.Body.Len())
+	}
+}
+
+func TestHandleStreamResponseClientCancel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstreamDone := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"id\":1}\n\n")
+		w.(http.Flusher).Flush()
+		// 模拟长时间生成，直到代理取消请求
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+			t.Error("Upstream request was not canceled")
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proxyService := NewProxyService(&config.ServerConfig{})
+	resp, err := proxyService.ProxyRequest(ctx, "POST", ts.URL, nil, []byte(`{"stream":true}`))
+	if err != nil {
+		t.Fatalf("ProxyRequest failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err = proxyService.HandleStreamResponse(ctx, c, resp, nil)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	<-upstreamDone
+	if !strings.Contains(w.Body.String(), `{"id":1}`) {
+		t.Errorf("Expected first event to be forwarded, got %q", w.Body.String())
+	}
+}
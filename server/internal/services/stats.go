@@ -2,7 +2,7 @@ package services
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"sort"
 	"strings"
@@ -24,21 +24,26 @@ type Stats struct {
 	TotalRequests      uint64
 	SuccessfulRequests uint64
 	FailedRequests     uint64
+	CanceledRequests   uint64 // 客户端在响应完成前断开的请求数
+	CacheHits          uint64 // 响应缓存命中次数
+	CacheMisses        uint64 // 响应缓存未命中次数
+	SemanticHits       uint64 // 语义缓存命中次数（同时计入 CacheHits）
+	CoalescedRequests  uint64 // 复用相同并发请求响应的请求数
 	BytesReceived      uint64
 	BytesSent          uint64
 	StartTime          time.Time
-	errorStats         sync.Map                       // 用于存储每个错误状态码的计数
-	Version            string                         // 版本号
-	ServerAddr         string                         // 服务器地址
-	Clients            map[string]config.ClientConfig // 客户端配置
+	errorStats         sync.Map                              // 用于存储每个错误状态码的计数
+	Version            string                                // 版本号
+	ServerAddr         string                                // 服务器地址
+	clients            func() map[string]config.ClientConfig // 返回当前客户端配置，配置可能在运行时变化
 }
 
-func NewStats(version, serverAddr string, clients map[string]config.ClientConfig) *Stats {
+func NewStats(version, serverAddr string, clients func() map[string]config.ClientConfig) *Stats {
 	return &Stats{
 		StartTime:  time.Now(),
 		Version:    version,
 		ServerAddr: serverAddr,
-		Clients:    clients,
+		clients:    clients,
 	}
 }
 
@@ -59,6 +64,32 @@ func (s *Stats) IncrementFailed() {
 	atomic.AddUint64(&s.FailedRequests, 1)
 }
 
+// IncrementCanceled 增加客户端断开的请求计数
+func (s *Stats) IncrementCanceled() {
+	atomic.AddUint64(&s.CanceledRequests, 1)
+}
+
+// IncrementCacheHit 增加响应缓存命中计数
+func (s *Stats) IncrementCacheHit() {
+	atomic.AddUint64(&s.CacheHits, 1)
+}
+
+// IncrementSemanticHit 增加语义缓存命中计数
+func (s *Stats) IncrementSemanticHit() {
+	atomic.AddUint64(&s.CacheHits, 1)
+	atomic.AddUint64(&s.SemanticHits, 1)
+}
+
+// IncrementCoalesced 增加合并请求计数
+func (s *Stats) IncrementCoalesced() {
+	atomic.AddUint64(&s.CoalescedRequests, 1)
+}
+
+// IncrementCacheMiss 增加响应缓存未命中计数
+func (s *Stats) IncrementCacheMiss() {
+	atomic.AddUint64(&s.CacheMisses, 1)
+}
+
 // IncrementErrorStatus 增加特定错误状态码的计数
 func (s *Stats) IncrementErrorStatus(statusCode int) {
 	if value, ok := s.errorStats.Load(statusCode); ok {
@@ -216,7 +247,7 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 			time.Sleep(50 * time.Millisecond)
 		}
 	}
-	fmt.Println("\n")
+	fmt.Print("\n\n")
 
 	// 显示进度条
 	width := 40
@@ -245,7 +276,7 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 		spinIdx = (spinIdx + 1) % len(spinChars)
 		time.Sleep(50 * time.Millisecond)
 	}
-	fmt.Println("\n")
+	fmt.Print("\n\n")
 
 	// 显示启动检查项，使用动画效果
 	checkItems := []struct {
@@ -297,7 +328,7 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 	// 创建一个函数来启动 UI
 	startUI := func() error {
 		if err := ui.Init(); err != nil {
-			log.Printf("failed to initialize termui: %v", err)
+			slog.Error("Failed to initialize termui", "error", err)
 			return err
 		}
 		uiActive = true
@@ -315,19 +346,32 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 	title := widgets.NewParagraph()
 	title.Title = fmt.Sprintf("RelayAPI Server (v%s | %s)", s.Version, s.ServerAddr)
 
-	// 添加客户端到列表
+	// 添加客户端到列表，客户端配置可能热加载，每次刷新界面时重新生成
 	var clientKeys []string
-	clientDetails := make(map[string]string)
-	var titleText strings.Builder
-	for hash, client := range s.Clients {
-		shortHash := hash[:12] + "..."
-		clientKeys = append(clientKeys, shortHash)
-		// 存储详细信息
-		maskedKey := client.Crypto.AESKey[:8] + "..." + client.Crypto.AESKey[len(client.Crypto.AESKey)-4:]
-		titleText.WriteString(fmt.Sprintf("%s | Key: %s | IV: %s\n", shortHash, maskedKey, client.Crypto.AESIVSeed))
-		clientDetails[shortHash] = fmt.Sprintf("Hash: %s\nKey: %s\nIV: %s", hash, maskedKey, client.Crypto.AESIVSeed)
+	var clientDetails map[string]string
+	refreshClients := func() {
+		clients := s.clients()
+		hashes := make([]string, 0, len(clients))
+		for hash := range clients {
+			hashes = append(hashes, hash)
+		}
+		sort.Strings(hashes)
+
+		clientKeys = clientKeys[:0]
+		clientDetails = make(map[string]string, len(clients))
+		var titleText strings.Builder
+		for _, hash := range hashes {
+			client := clients[hash]
+			shortHash := hash[:12] + "..."
+			clientKeys = append(clientKeys, shortHash)
+			// 存储详细信息
+			maskedKey := client.Crypto.AESKey[:8] + "..." + client.Crypto.AESKey[len(client.Crypto.AESKey)-4:]
+			titleText.WriteString(fmt.Sprintf("%s | Key: %s | IV: %s\n", shortHash, maskedKey, client.Crypto.AESIVSeed))
+			clientDetails[shortHash] = fmt.Sprintf("Hash: %s\nKey: %s\nIV: %s", hash, maskedKey, client.Crypto.AESIVSeed)
+		}
+		title.Text = titleText.String()
 	}
-	title.Text = titleText.String()
+	refreshClients()
 	title.TextStyle.Fg = ui.ColorCyan
 	title.BorderStyle.Fg = ui.ColorCyan
 	title.TitleStyle.Fg = ui.ColorCyan
@@ -392,7 +436,7 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 
 		if showingDetail {
 			// 显示详情模式
-			if len(clientKeys) > 0 {
+			if clientList.SelectedRow < len(clientKeys) {
 				selectedClient := clientKeys[clientList.SelectedRow]
 				clientDetail.Text = clientDetails[selectedClient]
 				// 居中显示详情
@@ -405,8 +449,9 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 			}
 		} else {
 			// 正常模式
+			refreshClients()
 			// 根据客户端数量计算标题高度
-			titleHeight := len(s.Clients) + 2 // 标题行 + 客户端行数 + 边框
+			titleHeight := len(clientKeys) + 2 // 标题行 + 客户端行数 + 边框
 			title.SetRect(0, 0, width, titleHeight)
 			basicStats.SetRect(0, titleHeight, width/2, (height+titleHeight)/2)
 			requestsPlot.SetRect(width/2, titleHeight, width, (height+titleHeight)/2)
@@ -418,6 +463,11 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 			totalReqs := atomic.LoadUint64(&s.TotalRequests)
 			successReqs := atomic.LoadUint64(&s.SuccessfulRequests)
 			failedReqs := atomic.LoadUint64(&s.FailedRequests)
+			canceledReqs := atomic.LoadUint64(&s.CanceledRequests)
+			cacheHits := atomic.LoadUint64(&s.CacheHits)
+			cacheMisses := atomic.LoadUint64(&s.CacheMisses)
+			semanticHits := atomic.LoadUint64(&s.SemanticHits)
+			coalescedReqs := atomic.LoadUint64(&s.CoalescedRequests)
 			bytesRecv := atomic.LoadUint64(&s.BytesReceived)
 			bytesSent := atomic.LoadUint64(&s.BytesSent)
 
@@ -462,6 +512,9 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 					"🔄 Total Requests: %d\n"+
 					"✅ Successful: %d\n"+
 					"❌ Failed: %d\n"+
+					"🔌 Canceled: %d\n"+
+					"💾 Cache Hit/Miss: %d/%d (semantic %d)\n"+
+					"🔗 Coalesced: %d\n"+
 					"📥 Bytes Received: %s\n"+
 					"📤 Bytes Sent: %s\n"+
 					"📊 Success Rate: %.2f%%",
@@ -469,6 +522,11 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 				totalReqs,
 				successReqs,
 				failedReqs,
+				canceledReqs,
+				cacheHits,
+				cacheMisses,
+				semanticHits,
+				coalescedReqs,
 				formatBytes(bytesRecv),
 				formatBytes(bytesSent),
 				successRate,
@@ -541,7 +599,7 @@ func (s *Stats) StartConsoleDisplay(stopChan chan struct{}) {
 				// 设置终端为原始模式
 				oldState, err := term.MakeRaw(int(syscall.Stdin))
 				if err != nil {
-					log.Printf("无法设置终端为原始模式: %v", err)
+					slog.Error("无法设置终端为原始模式", "error", err)
 					return
 				}
 
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"relayapi/server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上游超时错误，调用方可通过 errors.Is 判断
+var (
+	ErrFirstByteTimeout = errors.New("upstream did not respond within the first byte timeout")
+	ErrIdleTimeout      = errors.New("upstream stream was idle for too long")
+	ErrStreamTimeout    = errors.New("upstream stream exceeded the maximum duration")
+)
+
+// Timeouts 代理转发的超时设置，值为 0 表示不限制
+type Timeouts struct {
+	FirstByte time.Duration // 等待上游响应头的最长时间
+	Idle      time.Duration // 两次收到上游数据之间的最长间隔
+	Stream    time.Duration // 流式响应的最长持续时间
+	Keepalive time.Duration // 流式响应空闲时发送注释行的间隔
+}
+
+// NewTimeouts 根据服务器配置创建超时设置
+func NewTimeouts(cfg *config.ServerConfig) Timeouts {
+	timeouts := cfg.Proxy.Timeouts
+	return Timeouts{
+		FirstByte: time.Duration(timeouts.FirstByte) * time.Second,
+		Idle:      time.Duration(timeouts.Idle) * time.Second,
+		Stream:    time.Duration(timeouts.Stream) * time.Second,
+		Keepalive: time.Duration(timeouts.Keepalive) * time.Second,
+	}
+}
+
+// IsTimeout 判断错误是否由代理的上游超时引起
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrFirstByteTimeout) || errors.Is(err, ErrIdleTimeout) || errors.Is(err, ErrStreamTimeout)
+}
+
+// timeoutBody 包装上游响应体，超过空闲时间或总时长时取消上游请求
+type timeoutBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	idle   time.Duration
+
+	mu          sync.Mutex
+	idleTimer   *time.Timer
+	streamTimer *time.Timer
+}
+
+// newTimeoutBody 为响应体设置空闲计时器和总时长计时器
+func newTimeoutBody(ctx context.Context, cancel context.CancelCauseFunc, body io.ReadCloser, idle, stream time.Duration) *timeoutBody {
+	b := &timeoutBody{ReadCloser: body, ctx: ctx, cancel: cancel, idle: idle}
+	if idle > 0 {
+		b.idleTimer = time.AfterFunc(idle, func() { cancel(ErrIdleTimeout) })
+	}
+	if stream > 0 {
+		b.streamTimer = time.AfterFunc(stream, func() { cancel(ErrStreamTimeout) })
+	}
+	return b
+}
+
+// Read 每次收到数据都重置空闲计时器，超时取消后返回具体的超时原因
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && b.idleTimer != nil {
+		b.mu.Lock()
+		b.idleTimer.Reset(b.idle)
+		b.mu.Unlock()
+	}
+	if err != nil && err != io.EOF {
+		if cause := context.Cause(b.ctx); IsTimeout(cause) {
+			return n, cause
+		}
+	}
+	return n, err
+}
+
+// Close 停止计时器并释放上游请求的上下文
+func (b *timeoutBody) Close() error {
+	b.mu.Lock()
+	if b.idleTimer != nil {
+		b.idleTimer.Stop()
+	}
+	if b.streamTimer != nil {
+		b.streamTimer.Stop()
+	}
+	b.mu.Unlock()
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
+
+// isStreamingResponse 判断响应是否为长度未知的流式响应
+func isStreamingResponse(resp *http.Response) bool {
+	return resp.ContentLength < 0 || strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// ClearWriteDeadline 流式响应不受服务器全局 WriteTimeout 限制，由 FirstByte、Idle 和 Stream 超时控制时长。
+// WriteTimeout 从请求开始计时，流式请求需要在转发到上游之前调用，否则上游首字节较慢时连接会被提前关闭
+func ClearWriteDeadline(c *gin.Context) {
+	// 测试用的 ResponseRecorder 等不支持设置截止时间，忽略错误
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+}
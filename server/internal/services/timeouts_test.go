@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFirstByteTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	proxyService := &ProxyService{client: &http.Client{}, timeouts: Timeouts{FirstByte: 50 * time.Millisecond}}
+	_, err := proxyService.ProxyRequest(context.Background(), "POST", ts.URL, nil, nil)
+	if !errors.Is(err, ErrFirstByteTimeout) {
+		t.Errorf("Expected first byte timeout, got %v", err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	proxyService := &ProxyService{client: &http.Client{}, timeouts: Timeouts{Idle: 50 * time.Millisecond}}
+	resp, err := proxyService.ProxyRequest(context.Background(), "POST", ts.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("ProxyRequest failed: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	err = proxyService.HandleStreamResponse(context.Background(), c, resp, nil)
+	if !errors.Is(err, ErrIdleTimeout) {
+		t.Errorf("Expected idle timeout, got %v", err)
+	}
+	if !strings.Contains(w.Body.String(), "data: 1") {
+		t.Errorf("Expected data before timeout, got %q", w.Body.String())
+	}
+}
+
+func TestStreamKeepalive(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		time.Sleep(120 * time.Millisecond)
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer ts.Close()
+
+	proxyService := &ProxyService{client: &http.Client{}, timeouts: Timeouts{Keepalive: 30 * time.Millisecond}}
+	resp, err := proxyService.ProxyRequest(context.Background(), "POST", ts.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("ProxyRequest failed: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if err := proxyService.HandleStreamResponse(context.Background(), c, resp, nil); err != nil {
+		t.Fatalf("HandleStreamResponse failed: %v", err)
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, ": keepalive\n\n") || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Errorf("Unexpected stream body: %q", body)
+	}
+}
+
+func TestStreamFirstByteAfterWriteTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 首字节晚于服务器的 WriteTimeout 到达
+		time.Sleep(150 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\ndata: [DONE]\n\n")
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	proxyService := &ProxyService{client: &http.Client{}}
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		ClearWriteDeadline(c)
+		resp, err := proxyService.ProxyRequest(c.Request.Context(), "POST", upstream.URL, nil, nil)
+		if err != nil {
+			t.Errorf("ProxyRequest failed: %v", err)
+			return
+		}
+		if err := proxyService.HandleStreamResponse(c.Request.Context(), c, resp, nil); err != nil {
+			t.Errorf("HandleStreamResponse failed: %v", err)
+		}
+	})
+	// HTTP/2 下写超时到期会直接重置流，即使还没有写出任何数据
+	ts := httptest.NewUnstartedServer(router)
+	ts.EnableHTTP2 = true
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.StartTLS()
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(ts.URL, "application/json", strings.NewReader(`{"stream":true}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Reading stream failed: %v", err)
+	}
+	if !strings.HasSuffix(string(body), "data: [DONE]\n\n") {
+		t.Errorf("Expected the full stream, got %q", body)
+	}
+}
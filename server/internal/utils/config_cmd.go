@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"relayapi/server/internal/config"
+)
+
+// OnceCMDCheckConfig 检查服务器配置和客户端配置，打印全部问题及其字段路径后直接退出程序，
+// 存在问题时退出码为 1
+func OnceCMDCheckConfig(serverConfigPath, clientConfigPath string) {
+	problems := config.CheckConfigFiles(serverConfigPath, clientConfigPath)
+	if len(problems) == 0 {
+		fmt.Printf("✅ 配置有效: %s, %s\n", serverConfigPath, clientConfigPath)
+		os.Exit(0)
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", problem.Error())
+	}
+	fmt.Fprintf(os.Stderr, "共发现 %d 个问题\n", len(problems))
+	os.Exit(1)
+}
+
+// OnceCMDSchema 输出配置文件的 JSON Schema 并直接退出程序，name 为 server 或 client
+func OnceCMDSchema(name string) {
+	var schema *config.Schema
+	switch name {
+	case "server":
+		schema = config.ServerSchema()
+	case "client":
+		schema = config.ClientSchema()
+	default:
+		fmt.Fprintln(os.Stderr, "用法: relayapi-server --schema server|client")
+		os.Exit(1)
+	}
+
+	jsonData, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonData))
+	os.Exit(0)
+}
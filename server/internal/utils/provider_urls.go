@@ -5,7 +5,9 @@ var ProviderURLs = map[string]string{
 	"openai":                "https://api.openai.com/v1",
 	"dashscope":             "https://dashscope.aliyuncs.com/compatible-mode/v1",
 	"googleai":              "https://generativelanguage.googleapis.com/v1beta",
+	"googleai-openai":       "https://generativelanguage.googleapis.com/v1beta",
 	"anthropic":             "https://api.anthropic.com/v1",
+	"anthropic-openai":      "https://api.anthropic.com/v1",
 	"cohere":                "https://api.cohere.ai/v1",
 	"huggingface":           "https://api-inference.huggingface.co/models",
 	"replicate":             "https://api.replicate.com/v1",
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"relayapi/server/internal/config"
+)
+
+// OnceCMDRotateKey 为 .rai 文件生成下一代密钥并直接退出程序，
+// 旧密钥保留在 previous_keys 中，在退役时间之前仍可解密已签发的令牌
+func OnceCMDRotateKey(args []string) {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	raiPath := fs.String("rai", "default.rai", "要轮换的客户端配置文件路径 (.rai)")
+	retireAfter := fs.Duration("retire-after", 7*24*time.Hour, "旧密钥的保留时长，应不短于已签发令牌的最长有效期")
+	write := fs.Bool("write", false, "直接覆盖 .rai 文件，默认输出到标准输出")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: relayapi-server rotate --rai default.rai [--retire-after 168h] [--write]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := rotateKey(*raiPath, *retireAfter, *write); err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// rotateKey 读取 .rai 文件并生成轮换后的配置
+func rotateKey(raiPath string, retireAfter time.Duration, write bool) error {
+	if retireAfter <= 0 {
+		return fmt.Errorf("--retire-after 必须大于 0")
+	}
+	clientCfg, err := loadRAIFile(raiPath)
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateClientConfig(clientCfg); err != nil {
+		return fmt.Errorf("无效的 .rai 文件: %v", err)
+	}
+
+	retireAt := time.Now().UTC().Add(retireAfter).Truncate(time.Second)
+	rotated, err := config.RotateClientConfig(*clientCfg, retireAt)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(rotated, "", "    ")
+	if err != nil {
+		return fmt.Errorf("JSON 转换失败: %v", err)
+	}
+	if !write {
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	// 只替换 crypto 字段，其他字段中的 ${VAR} 和 *_file 引用保持不变
+	rewritten, err := config.RewriteClientCrypto(raiPath, &rotated)
+	if err != nil {
+		return fmt.Errorf("无法直接覆盖 .rai 文件，请去掉 --write 输出到标准输出后手动更新: %v", err)
+	}
+	if err := os.WriteFile(raiPath, rewritten, 0600); err != nil {
+		return fmt.Errorf("写入 .rai 文件失败: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "已轮换到第 %d 代密钥，旧密钥将于 %s 退役\n",
+		rotated.Crypto.Generation, retireAt.Format(time.RFC3339))
+	fmt.Fprintf(os.Stderr, "新的 rai_hash: %s\n", config.GenerateConfigHash(&rotated))
+	return nil
+}
@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"relayapi/server/internal/config"
+	"relayapi/server/internal/crypto"
+	"relayapi/server/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// printTokenUsage 打印令牌子命令的使用说明
+func printTokenUsage() {
+	fmt.Println("\n令牌子命令的用法:")
+	fmt.Println("1. 使用 .rai 文件签发令牌:")
+	fmt.Println("   relayapi-server token mint --rai default.rai --api-key sk-xxx --provider openai [--max-calls 100] [--expire 86400] [--ext-info '{}'] [--path /chat/completions]")
+	fmt.Println()
+	fmt.Println("2. 解密并查看令牌 (指定 --server 时从运行中的服务器读取使用次数):")
+	fmt.Println("   relayapi-server token inspect --rai default.rai [--server http://localhost:8840 --admin-token xxx] <token 或 URL>")
+	fmt.Println()
+	fmt.Println("3. 在运行中的服务器上校验令牌:")
+	fmt.Println("   relayapi-server token validate --server http://localhost:8840 --admin-token xxx [--rai-hash xxx] <token 或 URL>")
+	fmt.Println()
+}
+
+// OnceCMDToken 执行令牌子命令并直接退出程序
+func OnceCMDToken(args []string) {
+	if len(args) == 0 {
+		printTokenUsage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch args[0] {
+	case "mint":
+		err = mintTokenCmd(args[1:])
+	case "inspect":
+		err = inspectTokenCmd(args[1:])
+	case "validate":
+		err = validateTokenCmd(args[1:])
+	case "help", "-h", "--help":
+		printTokenUsage()
+	default:
+		err = fmt.Errorf("未知的子命令: %s", args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// mintTokenCmd 使用 .rai 文件签发令牌
+func mintTokenCmd(args []string) error {
+	fs := flag.NewFlagSet("token mint", flag.ExitOnError)
+	raiPath := fs.String("rai", "default.rai", "客户端配置文件路径 (.rai)")
+	apiKey := fs.String("api-key", "", "上游 API Key")
+	provider := fs.String("provider", "openai", "API 提供商")
+	maxCalls := fs.Int("max-calls", 100, "最大调用次数")
+	expire := fs.Int("expire", 24*60*60, "有效期（秒）")
+	extInfo := fs.String("ext-info", "", "扩展信息 (JSON 字符串)")
+	path := fs.String("path", "", "生成 URL 时附加的 API 路径")
+	fs.Parse(args)
+
+	if *apiKey == "" {
+		return fmt.Errorf("--api-key 不能为空")
+	}
+	if *extInfo != "" && !json.Valid([]byte(*extInfo)) {
+		return fmt.Errorf("--ext-info 必须是有效的 JSON")
+	}
+	clientCfg, err := loadRAIFile(*raiPath)
+	if err != nil {
+		return err
+	}
+	encryptor, err := crypto.NewEncryptor(clientCfg)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	token := &models.Token{
+		ID:         "token-" + uuid.New().String(),
+		APIKey:     *apiKey,
+		MaxCalls:   *maxCalls,
+		ExpireTime: now.Add(time.Duration(*expire) * time.Second),
+		CreatedAt:  now,
+		Provider:   *provider,
+		ExtInfo:    *extInfo,
+	}
+	encrypted, err := crypto.EncryptToken(encryptor, token)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(map[string]interface{}{
+		"id":          token.ID,
+		"token":       encrypted,
+		"rai_hash":    config.GenerateConfigHash(clientCfg),
+		"url":         clientCfg.APIURL(*path, encrypted),
+		"expire_time": token.ExpireTime,
+	})
+}
+
+// inspectTokenCmd 解密令牌并打印内容，使用次数只保存在服务器进程中
+func inspectTokenCmd(args []string) error {
+	fs := flag.NewFlagSet("token inspect", flag.ExitOnError)
+	raiPath := fs.String("rai", "default.rai", "客户端配置文件路径 (.rai)")
+	server := fs.String("server", "", "运行中的服务器地址，用于读取使用次数")
+	adminToken := fs.String("admin-token", "", "管理令牌")
+	fs.Parse(args)
+
+	encoded, _, err := tokenArg(fs.Args())
+	if err != nil {
+		return err
+	}
+	clientCfg, err := loadRAIFile(*raiPath)
+	if err != nil {
+		return err
+	}
+	keyRing, err := crypto.NewKeyRing(clientCfg)
+	if err != nil {
+		return err
+	}
+	tokenBytes, err := crypto.DecodeToken(encoded)
+	if err != nil {
+		return err
+	}
+	token, generation, err := keyRing.Open(tokenBytes)
+	if err != nil {
+		return err
+	}
+
+	info := token.Info()
+	info.KeyGeneration = generation
+	if *server != "" {
+		var usage struct {
+			UsedCalls int `json:"used_calls"`
+		}
+		usageURL := strings.TrimRight(*server, "/") + "/admin/tokens/" + url.PathEscape(token.ID) + "/usage"
+		if err := adminCall(http.MethodGet, usageURL, *adminToken, nil, &usage); err != nil {
+			return err
+		}
+		info.UsedCalls = usage.UsedCalls
+		info.RemainingCalls = token.MaxCalls - usage.UsedCalls
+		if info.Reason != "expired" {
+			info.Reason = ""
+			if info.RemainingCalls <= 0 {
+				info.Reason = "usage_exceeded"
+			}
+		}
+		info.Valid = info.Reason == ""
+	} else {
+		fmt.Fprintln(os.Stderr, "提示: 使用次数保存在服务器进程中，指定 --server 读取实际的使用次数")
+	}
+	return printJSON(info)
+}
+
+// validateTokenCmd 由运行中的服务器解密并校验令牌，令牌无效时返回错误
+func validateTokenCmd(args []string) error {
+	fs := flag.NewFlagSet("token validate", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8840", "运行中的服务器地址")
+	adminToken := fs.String("admin-token", "", "管理令牌")
+	raiHash := fs.String("rai-hash", "", "加密令牌的客户端配置 hash")
+	fs.Parse(args)
+
+	encoded, urlHash, err := tokenArg(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *raiHash == "" {
+		*raiHash = urlHash
+	}
+
+	body, _ := json.Marshal(map[string]string{"token": encoded, "rai_hash": *raiHash})
+	var info models.TokenInfo
+	inspectURL := strings.TrimRight(*server, "/") + "/relayapi-admin/tokens/inspect"
+	if err := adminCall(http.MethodPost, inspectURL, *adminToken, body, &info); err != nil {
+		return err
+	}
+	if err := printJSON(info); err != nil {
+		return err
+	}
+	if !info.Valid {
+		return fmt.Errorf("令牌无效: %s", info.Reason)
+	}
+	return nil
+}
+
+// tokenArg 从参数中取出令牌，也接受带 token 和 rai_hash 参数的完整 URL
+func tokenArg(args []string) (token, raiHash string, err error) {
+	if len(args) != 1 {
+		return "", "", fmt.Errorf("需要且只需要一个令牌参数")
+	}
+	arg := args[0]
+	if strings.Contains(arg, "token=") {
+		parsed, err := url.Parse(arg)
+		if err != nil {
+			return "", "", fmt.Errorf("无效的 URL: %v", err)
+		}
+		query := parsed.Query()
+		return query.Get("token"), query.Get("rai_hash"), nil
+	}
+	return arg, "", nil
+}
+
+// loadRAIFile 读取 .rai 客户端配置文件，与服务器相同地支持 YAML/TOML、${VAR} 和 *_file 字段
+func loadRAIFile(path string) (*config.ClientConfig, error) {
+	clientCfg, err := config.ReadClientConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 .rai 文件失败: %v", err)
+	}
+	return &clientCfg, nil
+}
+
+// adminCall 调用服务器的管理接口并解析 JSON 响应
+func adminCall(method, target, adminToken string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求服务器失败: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("服务器返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+// printJSON 以缩进格式打印 JSON
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}